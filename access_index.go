@@ -0,0 +1,432 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/registry/rbac/validation"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// indexSubject identifies a subject in the AccessIndex.
+// ServiceAccounts are indexed as users by their username.
+type indexSubject struct {
+	Kind string
+	Name string
+}
+
+// namespaceAccess is the set of namespaces a user has access to
+type namespaceAccess struct {
+	all   bool
+	names sets.Set[string]
+}
+
+// Has returns whether the user has access to the given namespace
+func (a namespaceAccess) Has(name string) bool {
+	return a.all || a.names.Has(name)
+}
+
+// AccessIndex indexes the namespaces on which each subject (user, group, or ServiceAccount)
+// is granted `get namespaces`.
+// The index is rebuilt from the cached RBAC resources on the first request following
+// a call to Invalidate. Update is expected to be invoked on any change of the cached resources:
+// it applies the RoleBindings changes to the index and invalidates it only when needed.
+type AccessIndex struct {
+	ctx      context.Context
+	reader   client.Reader
+	resolver *validation.DefaultRuleResolver
+	l        *slog.Logger
+
+	dirty atomic.Bool
+
+	mu sync.RWMutex
+	// allNamespaces contains the names of all the namespaces, sorted
+	allNamespaces []string
+	// clusterWide contains the subjects granted access to every namespace
+	clusterWide sets.Set[indexSubject]
+	// namespaces contains the namespaces each subject is granted access to
+	namespaces map[indexSubject]sets.Set[string]
+	// grants counts, for each subject and namespace, the bindings granting access to the namespace
+	grants map[indexSubject]map[string]int
+	// roleBindings contains the subjects each RoleBinding grants access to its namespace
+	roleBindings map[types.NamespacedName]sets.Set[indexSubject]
+}
+
+func NewAccessIndex(ctx context.Context, reader client.Reader, l *slog.Logger) *AccessIndex {
+	i := &AccessIndex{
+		ctx:      ctx,
+		reader:   reader,
+		resolver: NewRuleResolver(ctx, reader, l),
+		l:        l,
+	}
+	i.dirty.Store(true)
+	return i
+}
+
+// Invalidate marks the index as outdated, it will be rebuilt on the next request
+func (i *AccessIndex) Invalidate() {
+	i.dirty.Store(true)
+}
+
+// Update applies the change of the given cached objects to the index.
+// RoleBindings changes are applied incrementally and Namespaces updates are ignored,
+// as only the Namespaces' names are indexed. Any other change invalidates the index.
+func (i *AccessIndex) Update(objs []any) {
+	switch {
+	case isNamespaceUpdate(objs):
+		return
+	case !isRoleBindingChange(objs):
+		i.Invalidate()
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// a pending rebuild will include the change
+	if i.dirty.Load() {
+		return
+	}
+
+	// the changed RoleBindings are read back from the cache, so that
+	// both additions and deletions are applied, even if the rebuild already included them.
+	// The index content is copied before being modified, as it may be visited without holding the lock
+	namespaces, copied := maps.Clone(i.namespaces), sets.New[indexSubject]()
+	copyOnWrite := func(ss ...indexSubject) {
+		for _, is := range ss {
+			if names, ok := namespaces[is]; ok && !copied.Has(is) {
+				namespaces[is] = names.Clone()
+			}
+			copied.Insert(is)
+		}
+	}
+	for _, o := range objs {
+		rb := unwrapDeleted(o).(*rbacv1.RoleBinding)
+		key := types.NamespacedName{Namespace: rb.Namespace, Name: rb.Name}
+
+		crb := rbacv1.RoleBinding{}
+		err := i.reader.Get(i.ctx, key, &crb)
+		if err != nil && !kerrors.IsNotFound(err) {
+			i.l.Debug("error getting rolebinding, invalidating the access index", "namespace", key.Namespace, "rolebinding", key.Name, "error", err)
+			i.dirty.Store(true)
+			return
+		}
+
+		copyOnWrite(i.roleBindings[key].UnsortedList()...)
+		i.revokeRoleBinding(namespaces, key)
+		if err == nil {
+			copyOnWrite(roleBindingSubjects(&crb).UnsortedList()...)
+			i.grantRoleBinding(namespaces, &crb)
+		}
+	}
+	i.namespaces = namespaces
+}
+
+// NamespacesFor returns the namespaces the user has `get` access on
+func (i *AccessIndex) NamespacesFor(ctx context.Context, ui user.Info) (namespaceAccess, error) {
+	if err := i.refresh(ctx); err != nil {
+		return namespaceAccess{}, err
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	a := namespaceAccess{names: sets.New[string]()}
+	for _, s := range userIndexSubjects(ui) {
+		if i.clusterWide.Has(s) {
+			return namespaceAccess{all: true}, nil
+		}
+		a.names = a.names.Union(i.namespaces[s])
+	}
+	return a, nil
+}
+
+// VisitMatrix invokes visit, in order of subject, with the namespaces each subject is granted access to.
+// Subjects granted access to every namespace are visited with all the namespaces.
+// The visit stops at the first error, which is returned.
+func (i *AccessIndex) VisitMatrix(ctx context.Context, visit func(MatrixRow) error) error {
+	if err := i.refresh(ctx); err != nil {
+		return err
+	}
+
+	// a rebuild replaces the index content without modifying it,
+	// the visit can then proceed without holding the lock
+	i.mu.RLock()
+	allNamespaces, clusterWide, namespaces := i.allNamespaces, i.clusterWide, i.namespaces
+	i.mu.RUnlock()
+
+	ss := clusterWide.Clone()
+	for s := range namespaces {
+		ss.Insert(s)
+	}
+	subjects := ss.UnsortedList()
+	slices.SortFunc(subjects, func(a, b indexSubject) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+
+	for _, s := range subjects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row := newMatrixRow(s)
+		if clusterWide.Has(s) {
+			row.ClusterWide, row.Namespaces = true, allNamespaces
+		} else {
+			row.Namespaces = sets.List(namespaces[s])
+		}
+		if err := visit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refresh rebuilds the index if it is outdated
+func (i *AccessIndex) refresh(ctx context.Context) error {
+	if !i.dirty.Load() {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// the index may have been rebuilt while waiting for the lock
+	if !i.dirty.Load() {
+		return nil
+	}
+
+	// changes notified from now on will require a new rebuild
+	i.dirty.Store(false)
+	if err := i.build(ctx); err != nil {
+		i.dirty.Store(true)
+		return err
+	}
+	return nil
+}
+
+// build computes the index from the cached resources
+func (i *AccessIndex) build(ctx context.Context) error {
+	nn := corev1.NamespaceList{}
+	if err := i.reader.List(ctx, &nn); err != nil {
+		return err
+	}
+	crbb := rbacv1.ClusterRoleBindingList{}
+	if err := i.reader.List(ctx, &crbb); err != nil {
+		return err
+	}
+	rbb := rbacv1.RoleBindingList{}
+	if err := i.reader.List(ctx, &rbb); err != nil {
+		return err
+	}
+
+	clusterWide := sets.New[indexSubject]()
+	i.namespaces = map[indexSubject]sets.Set[string]{}
+	i.grants = map[indexSubject]map[string]int{}
+	i.roleBindings = map[types.NamespacedName]sets.Set[indexSubject]{}
+
+	// ClusterRoleBindings grant access to all namespaces or to the ones in the rules' resourceNames
+	for _, crb := range crbb.Items {
+		rr, err := i.resolver.GetRoleReferenceRules(crb.RoleRef, "")
+		if err != nil {
+			i.l.Debug("skipping clusterrolebinding", "clusterrolebinding", crb.Name, "error", err)
+			continue
+		}
+
+		all, names := namespacesGrantedByRules(rr)
+		if all {
+			for _, s := range crb.Subjects {
+				if is, ok := newIndexSubject(s, ""); ok {
+					clusterWide.Insert(is)
+				}
+			}
+			continue
+		}
+
+		for _, s := range crb.Subjects {
+			is, ok := newIndexSubject(s, "")
+			if !ok {
+				continue
+			}
+			for _, ns := range nn.Items {
+				if names.Has(ns.Name) {
+					i.grant(i.namespaces, is, ns.Name)
+				}
+			}
+		}
+	}
+
+	// RoleBindings grant access to their own namespace only
+	for _, rb := range rbb.Items {
+		i.grantRoleBinding(i.namespaces, &rb)
+	}
+
+	allNamespaces := make([]string, 0, len(nn.Items))
+	for _, ns := range nn.Items {
+		allNamespaces = append(allNamespaces, ns.Name)
+	}
+	slices.Sort(allNamespaces)
+
+	i.allNamespaces, i.clusterWide = allNamespaces, clusterWide
+	i.l.Debug("access index rebuilt", "subjects", len(i.namespaces), "cluster-wide subjects", clusterWide.Len())
+	return nil
+}
+
+// grantRoleBinding records the access the RoleBinding grants to its namespace in namespaces
+func (i *AccessIndex) grantRoleBinding(namespaces map[indexSubject]sets.Set[string], rb *rbacv1.RoleBinding) {
+	rr, err := i.resolver.GetRoleReferenceRules(rb.RoleRef, rb.Namespace)
+	if err != nil {
+		i.l.Debug("skipping rolebinding", "namespace", rb.Namespace, "rolebinding", rb.Name, "error", err)
+		return
+	}
+	if all, names := namespacesGrantedByRules(rr); !all && !names.Has(rb.Namespace) {
+		return
+	}
+
+	ss := roleBindingSubjects(rb)
+	for is := range ss {
+		i.grant(namespaces, is, rb.Namespace)
+	}
+	i.roleBindings[types.NamespacedName{Namespace: rb.Namespace, Name: rb.Name}] = ss
+}
+
+// revokeRoleBinding removes the access the RoleBinding granted from namespaces
+func (i *AccessIndex) revokeRoleBinding(namespaces map[indexSubject]sets.Set[string], key types.NamespacedName) {
+	for is := range i.roleBindings[key] {
+		i.revoke(namespaces, is, key.Namespace)
+	}
+	delete(i.roleBindings, key)
+}
+
+// grant records a binding granting access to the namespace to the subject
+func (i *AccessIndex) grant(namespaces map[indexSubject]sets.Set[string], is indexSubject, ns string) {
+	if _, ok := i.grants[is]; !ok {
+		i.grants[is] = map[string]int{}
+	}
+	i.grants[is][ns]++
+
+	if _, ok := namespaces[is]; !ok {
+		namespaces[is] = sets.New[string]()
+	}
+	namespaces[is].Insert(ns)
+}
+
+// revoke removes a binding granting access to the namespace to the subject
+func (i *AccessIndex) revoke(namespaces map[indexSubject]sets.Set[string], is indexSubject, ns string) {
+	if i.grants[is][ns]--; i.grants[is][ns] > 0 {
+		return
+	}
+
+	delete(i.grants[is], ns)
+	namespaces[is].Delete(ns)
+	if len(i.grants[is]) == 0 {
+		delete(i.grants, is)
+		delete(namespaces, is)
+	}
+}
+
+// roleBindingSubjects returns the index subjects of the RoleBinding
+func roleBindingSubjects(rb *rbacv1.RoleBinding) sets.Set[indexSubject] {
+	ss := sets.New[indexSubject]()
+	for _, s := range rb.Subjects {
+		if is, ok := newIndexSubject(s, rb.Namespace); ok {
+			ss.Insert(is)
+		}
+	}
+	return ss
+}
+
+// isNamespaceUpdate returns whether the objects are the old and new versions of an updated Namespace
+func isNamespaceUpdate(objs []any) bool {
+	if len(objs) != 2 {
+		return false
+	}
+	_, oldOk := objs[0].(*corev1.Namespace)
+	_, newOk := objs[1].(*corev1.Namespace)
+	return oldOk && newOk
+}
+
+// isRoleBindingChange returns whether the objects are all RoleBindings
+func isRoleBindingChange(objs []any) bool {
+	if len(objs) == 0 {
+		return false
+	}
+	for _, o := range objs {
+		if _, ok := unwrapDeleted(o).(*rbacv1.RoleBinding); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// unwrapDeleted returns the object a deletion was notified for when its final state is unknown
+func unwrapDeleted(obj any) any {
+	if d, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		return d.Obj
+	}
+	return obj
+}
+
+// userIndexSubjects returns the subjects matching the user: the user itself and its groups
+func userIndexSubjects(ui user.Info) []indexSubject {
+	ss := make([]indexSubject, 0, len(ui.GetGroups())+1)
+	ss = append(ss, indexSubject{Kind: rbacv1.UserKind, Name: ui.GetName()})
+	for _, g := range ui.GetGroups() {
+		ss = append(ss, indexSubject{Kind: rbacv1.GroupKind, Name: g})
+	}
+	return ss
+}
+
+// newIndexSubject converts an RBAC subject to an indexSubject.
+// As the RBAC authorizer does, ServiceAccounts without namespace default to the binding's one.
+func newIndexSubject(s rbacv1.Subject, bindingNamespace string) (indexSubject, bool) {
+	switch s.Kind {
+	case rbacv1.UserKind, rbacv1.GroupKind:
+		return indexSubject{Kind: s.Kind, Name: s.Name}, true
+	case rbacv1.ServiceAccountKind:
+		ns := s.Namespace
+		if ns == "" {
+			ns = bindingNamespace
+		}
+		if ns == "" {
+			return indexSubject{}, false
+		}
+		return indexSubject{Kind: rbacv1.UserKind, Name: serviceaccount.MakeUsername(ns, s.Name)}, true
+	default:
+		return indexSubject{}, false
+	}
+}
+
+// namespacesGrantedByRules returns whether the rules grant `get` on all namespaces and,
+// if not, the names of the namespaces they grant `get` on.
+func namespacesGrantedByRules(rr []rbacv1.PolicyRule) (bool, sets.Set[string]) {
+	names := sets.New[string]()
+	for _, r := range rr {
+		// a rule without resourceNames allows every namespace
+		if rbac.RuleAllows(DefaultAccessCheck.Attributes(""), &r) {
+			return true, nil
+		}
+
+		// otherwise, check the rule would allow the namespace if the names matched
+		nr := r
+		nr.ResourceNames = nil
+		if rbac.RuleAllows(DefaultAccessCheck.Attributes(""), &nr) {
+			names.Insert(r.ResourceNames...)
+		}
+	}
+	return false, names
+}
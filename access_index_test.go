@@ -0,0 +1,194 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("AccessIndex", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+		ui     = &user.DefaultInfo{Name: "user", Groups: []string{"system:authenticated"}}
+
+		cli   client.Client
+		index *namespacelister.AccessIndex
+		nsl   namespacelister.NamespaceLister
+	)
+
+	listedNames := func() []string {
+		nn, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+		names := []string{}
+		for _, ns := range nn.Items {
+			names = append(names, ns.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		cli = fake.NewClientBuilder().WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}},
+				},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-1"},
+				Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+			},
+		).Build()
+		index = namespacelister.NewAccessIndex(ctx, cli, logger)
+		nsl = namespacelister.NewIndexedNamespaceLister(cli, index, nil, logger)
+	})
+
+	It("is rebuilt when invalidated", func() {
+		// given
+		Expect(listedNames()).To(ConsistOf("myns-1"))
+		Expect(cli.Create(ctx, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-2"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+		})).To(Succeed())
+
+		// when
+		index.Invalidate()
+
+		// then
+		Expect(listedNames()).To(ConsistOf("myns-1", "myns-2"))
+	})
+
+	It("is not rebuilt until invalidated", func() {
+		// given
+		Expect(listedNames()).To(ConsistOf("myns-1"))
+
+		// when
+		Expect(cli.DeleteAllOf(ctx, &rbacv1.RoleBinding{}, client.InNamespace("myns-1"))).To(Succeed())
+
+		// then
+		Expect(listedNames()).To(ConsistOf("myns-1"))
+		index.Invalidate()
+		Expect(listedNames()).To(BeEmpty())
+	})
+
+	It("ignores bindings referencing missing roles", func() {
+		// given
+		Expect(cli.Create(ctx, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing:user", Namespace: "myns-2"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "missing"},
+		})).To(Succeed())
+
+		// then
+		Expect(listedNames()).To(ConsistOf("myns-1"))
+	})
+	It("visits the namespaces each subject has access to", func() {
+		// given
+		Expect(cli.Create(ctx, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns-get:admins"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "admins"}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+		})).To(Succeed())
+		Expect(cli.Create(ctx, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns-get:mysa", Namespace: "myns-2"},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "mysa"}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+		})).To(Succeed())
+
+		// when
+		rows := []namespacelister.MatrixRow{}
+		err := index.VisitMatrix(ctx, func(row namespacelister.MatrixRow) error {
+			rows = append(rows, row)
+			return nil
+		})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rows).To(Equal([]namespacelister.MatrixRow{
+			{Kind: "Group", Name: "admins", ClusterWide: true, Namespaces: []string{"myns-1", "myns-2"}},
+			{Kind: "ServiceAccount", Namespace: "myns-2", Name: "mysa", Namespaces: []string{"myns-2"}},
+			{Kind: "User", Name: "user", Namespaces: []string{"myns-1"}},
+		}))
+	})
+
+	Context("on changes", func() {
+		nsGetRoleBinding := func(namespace, name string) *rbacv1.RoleBinding {
+			return &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+			}
+		}
+
+		It("applies the RoleBindings changes without being rebuilt", func() {
+			// given
+			Expect(listedNames()).To(ConsistOf("myns-1"))
+			// a rebuild would grant the user access to every namespace
+			Expect(cli.Create(ctx, &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user"},
+				Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+			})).To(Succeed())
+			Expect(cli.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "myns-3"}})).To(Succeed())
+
+			// when
+			rb := nsGetRoleBinding("myns-2", "ns-get:user")
+			Expect(cli.Create(ctx, rb)).To(Succeed())
+			index.Update([]any{rb})
+			old := nsGetRoleBinding("myns-1", "ns-get:user")
+			Expect(cli.Delete(ctx, old)).To(Succeed())
+			index.Update([]any{old})
+
+			// then
+			Expect(listedNames()).To(ConsistOf("myns-2"))
+		})
+
+		It("keeps the access granted by other RoleBindings", func() {
+			// given
+			rb := nsGetRoleBinding("myns-1", "ns-get:user-2")
+			Expect(cli.Create(ctx, rb)).To(Succeed())
+			index.Update([]any{rb})
+			Expect(listedNames()).To(ConsistOf("myns-1"))
+
+			// when
+			Expect(cli.Delete(ctx, rb)).To(Succeed())
+			index.Update([]any{rb})
+
+			// then
+			Expect(listedNames()).To(ConsistOf("myns-1"))
+		})
+
+		It("ignores the Namespaces updates", func() {
+			// given
+			Expect(listedNames()).To(ConsistOf("myns-1"))
+			Expect(cli.DeleteAllOf(ctx, &rbacv1.RoleBinding{}, client.InNamespace("myns-1"))).To(Succeed())
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}}
+
+			// when
+			index.Update([]any{ns, ns})
+
+			// then
+			Expect(listedNames()).To(ConsistOf("myns-1"))
+			index.Update([]any{ns})
+			Expect(listedNames()).To(BeEmpty())
+		})
+	})
+})
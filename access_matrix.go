@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+)
+
+const (
+	// MatrixFormatJSONLines writes a JSON object per subject
+	MatrixFormatJSONLines string = "jsonl"
+	// MatrixFormatCSV writes a CSV record per subject and namespace
+	MatrixFormatCSV string = "csv"
+)
+
+// MatrixRow lists the namespaces a subject is granted `get` access on
+type MatrixRow struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// ClusterWide is true if the subject is granted access to every namespace
+	ClusterWide bool     `json:"clusterWide"`
+	Namespaces  []string `json:"namespaces"`
+}
+
+// newMatrixRow returns the row for the given subject.
+// ServiceAccounts, indexed as users, are reported as ServiceAccounts.
+func newMatrixRow(s indexSubject) MatrixRow {
+	if s.Kind == rbacv1.UserKind {
+		if ns, name, err := serviceaccount.SplitUsername(s.Name); err == nil {
+			return MatrixRow{Kind: rbacv1.ServiceAccountKind, Namespace: ns, Name: name}
+		}
+	}
+	return MatrixRow{Kind: s.Kind, Name: s.Name}
+}
+
+// MatrixWriter writes the rows of the access matrix in a given format
+type MatrixWriter interface {
+	Write(MatrixRow) error
+	Flush() error
+}
+
+// NewMatrixWriter returns a MatrixWriter for the given format, and the content type of the output
+func NewMatrixWriter(w io.Writer, format string) (MatrixWriter, string, error) {
+	switch format {
+	case MatrixFormatJSONLines:
+		return &jsonLinesMatrixWriter{enc: json.NewEncoder(w)}, HttpContentTypeJSONLines, nil
+	case MatrixFormatCSV:
+		return &csvMatrixWriter{w: csv.NewWriter(w)}, HttpContentTypeCSV, nil
+	default:
+		return nil, "", fmt.Errorf("format %q is not supported, supported formats are %s and %s", format, MatrixFormatJSONLines, MatrixFormatCSV)
+	}
+}
+
+type jsonLinesMatrixWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonLinesMatrixWriter) Write(row MatrixRow) error {
+	return w.enc.Encode(row)
+}
+
+func (w *jsonLinesMatrixWriter) Flush() error {
+	return nil
+}
+
+// csvMatrixWriter writes a header followed by a record per subject and namespace
+type csvMatrixWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvMatrixWriter) Write(row MatrixRow) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.w.Write([]string{"kind", "subjectNamespace", "name", "clusterWide", "namespace"}); err != nil {
+			return err
+		}
+	}
+
+	for _, ns := range row.Namespaces {
+		if err := w.w.Write([]string{row.Kind, row.Namespace, row.Name, strconv.FormatBool(row.ClusterWide), ns}); err != nil {
+			return err
+		}
+	}
+	return w.w.Error()
+}
+
+func (w *csvMatrixWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	x509request "k8s.io/apiserver/pkg/authentication/request/x509"
+	tokencache "k8s.io/apiserver/pkg/authentication/token/cache"
+)
+
+// tokenReviewFailureCacheTTL is the duration for which rejected tokens are cached
+const tokenReviewFailureCacheTTL = 10 * time.Second
+
+// errMissingUserHeader reports a request without the header carrying the username
+var errMissingUserHeader = errors.New("missing user header")
+
+var (
+	_ authenticator.Request = &HeaderAuthenticator{}
+	_ authenticator.Token   = &tokenReviewAuthenticator{}
+)
+
+// HeaderAuthenticator trusts the user identity provided in the request headers.
+// Requests need to be authenticated by another component (e.g. a reverse proxy).
+type HeaderAuthenticator struct {
+	headers UserHeaders
+}
+
+func NewHeaderAuthenticator(headers UserHeaders) *HeaderAuthenticator {
+	return &HeaderAuthenticator{
+		headers: headers,
+	}
+}
+
+// AuthenticateRequest rejects requests without the username header,
+// instead of evaluating the access of an empty username.
+func (a *HeaderAuthenticator) AuthenticateRequest(r *http.Request) (*authenticator.Response, bool, error) {
+	if r.Header.Get(a.headers.Username) == "" {
+		return nil, false, fmt.Errorf("%w %s", errMissingUserHeader, a.headers.Username)
+	}
+	return &authenticator.Response{User: userInfoFromHeaders(r.Header, a.headers)}, true, nil
+}
+
+// NewFrontProxyAuthenticator builds an authenticator that requires the request to present
+// a client certificate signed by one of the clientCAs before delegating to auth.
+// If allowedNames is not empty, the certificate's Common Name must be one of them.
+// It follows kube-apiserver's `--requestheader-*` model.
+func NewFrontProxyAuthenticator(clientCAs *x509.CertPool, allowedNames []string, auth authenticator.Request) authenticator.Request {
+	opts := x509request.DefaultVerifyOptions()
+	opts.Roots = clientCAs
+	return x509request.NewVerifier(opts, auth, sets.NewString(allowedNames...))
+}
+
+// loadCertPool reads the PEM-encoded certificates in the given file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA file: %w", err)
+	}
+
+	p := x509.NewCertPool()
+	if !p.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("error reading CA file %s: no valid certificates found", path)
+	}
+	return p, nil
+}
+
+// TokenReviewer creates TokenReviews.
+// It is implemented by `k8s.io/client-go/kubernetes/typed/authentication/v1.TokenReviewInterface`.
+type TokenReviewer interface {
+	Create(ctx context.Context, tokenReview *authenticationv1.TokenReview, opts metav1.CreateOptions) (*authenticationv1.TokenReview, error)
+}
+
+// NewTokenReviewAuthenticator builds an authenticator that validates the request's Bearer token
+// with a TokenReview. Results are cached for the given TTL.
+func NewTokenReviewAuthenticator(reviewer TokenReviewer, cacheTTL time.Duration) authenticator.Request {
+	ta := &tokenReviewAuthenticator{reviewer: reviewer}
+	return bearertoken.New(tokencache.New(ta, false, cacheTTL, min(cacheTTL, tokenReviewFailureCacheTTL)))
+}
+
+type tokenReviewAuthenticator struct {
+	reviewer TokenReviewer
+}
+
+func (a *tokenReviewAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	tr, err := a.reviewer.Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("error reviewing token: %w", err)
+	}
+
+	if !tr.Status.Authenticated {
+		if tr.Status.Error != "" {
+			return nil, false, errors.New(tr.Status.Error)
+		}
+		return nil, false, nil
+	}
+
+	u := tr.Status.User
+	var extra map[string][]string
+	if len(u.Extra) > 0 {
+		extra = make(map[string][]string, len(u.Extra))
+		for k, v := range u.Extra {
+			extra[k] = v
+		}
+	}
+	return &authenticator.Response{User: newUserInfo(u.Username, u.UID, u.Groups, extra)}, true, nil
+}
@@ -0,0 +1,331 @@
+package main_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type TokenReviewerMock func(ctx context.Context, tokenReview *authenticationv1.TokenReview, opts metav1.CreateOptions) (*authenticationv1.TokenReview, error)
+
+func (m TokenReviewerMock) Create(ctx context.Context, tokenReview *authenticationv1.TokenReview, opts metav1.CreateOptions) (*authenticationv1.TokenReview, error) {
+	return m(ctx, tokenReview, opts)
+}
+
+// newCertificate creates a certificate with the given Common Name signed by parent.
+// If parent is nil, a self-signed CA certificate is created.
+func newCertificate(cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	Expect(err).NotTo(HaveOccurred())
+	c, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return c, key
+}
+
+var _ = Describe("HeaderAuthenticator", func() {
+	const (
+		userHeader        = "X-Email"
+		uidHeader         = "X-Uid"
+		groupsHeader      = "X-Groups"
+		extraHeaderPrefix = "X-Extra-"
+	)
+
+	var authn *namespacelister.HeaderAuthenticator
+
+	BeforeEach(func() {
+		authn = namespacelister.NewHeaderAuthenticator(namespacelister.UserHeaders{
+			Username:      userHeader,
+			UID:           uidHeader,
+			Groups:        groupsHeader,
+			ExtraPrefixes: []string{extraHeaderPrefix},
+		})
+	})
+
+	It("does not authenticate requests without the username header", func() {
+		// given
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(groupsHeader, "mygroup")
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(err).To(MatchError(ContainSubstring(userHeader)))
+		Expect(ok).To(BeFalse())
+		Expect(rs).To(BeNil())
+	})
+
+	DescribeTable("authenticates the user with the groups from the request", func(headerValues []string, expectedGroups []string) {
+		// given
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(userHeader, "myuser")
+		for _, v := range headerValues {
+			r.Header.Add(groupsHeader, v)
+		}
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal("myuser"))
+		Expect(rs.User.GetGroups()).To(Equal(expectedGroups))
+	},
+		Entry("no groups", nil, []string{"system:authenticated"}),
+		Entry("single group", []string{"mygroup"}, []string{"mygroup", "system:authenticated"}),
+		Entry("comma-separated groups", []string{"mygroup-1, mygroup-2"}, []string{"mygroup-1", "mygroup-2", "system:authenticated"}),
+		Entry("repeated header", []string{"mygroup-1", "mygroup-2,mygroup-3"}, []string{"mygroup-1", "mygroup-2", "mygroup-3", "system:authenticated"}),
+		Entry("system:authenticated already provided", []string{"system:authenticated", "mygroup"}, []string{"system:authenticated", "mygroup"}),
+	)
+
+	It("authenticates ServiceAccounts with their implicit groups", func() {
+		// given
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(userHeader, "system:serviceaccount:myns:mysa")
+		r.Header.Add(groupsHeader, "mygroup")
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal("system:serviceaccount:myns:mysa"))
+		Expect(rs.User.GetGroups()).To(Equal([]string{
+			"mygroup",
+			"system:serviceaccounts",
+			"system:serviceaccounts:myns",
+			"system:authenticated",
+		}))
+	})
+
+	It("authenticates the user with the UID and extra attributes from the request", func() {
+		// given
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(userHeader, "myuser")
+		r.Header.Add(uidHeader, "myuid")
+		r.Header.Add(extraHeaderPrefix+"Scopes", "openid")
+		r.Header.Add(extraHeaderPrefix+"Scopes", "email")
+		r.Header.Add(extraHeaderPrefix+"Example.com%2fteam", "myteam")
+		r.Header.Add("X-Other", "other")
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal("myuser"))
+		Expect(rs.User.GetUID()).To(Equal("myuid"))
+		Expect(rs.User.GetExtra()).To(Equal(map[string][]string{
+			"scopes":           {"openid", "email"},
+			"example.com/team": {"myteam"},
+		}))
+	})
+})
+
+var _ = Describe("TokenReviewAuthenticator", func() {
+	var (
+		reviews  int
+		reviewer TokenReviewerMock
+	)
+
+	BeforeEach(func() {
+		reviews = 0
+		reviewer = TokenReviewerMock(func(ctx context.Context, tr *authenticationv1.TokenReview, opts metav1.CreateOptions) (*authenticationv1.TokenReview, error) {
+			reviews++
+			switch tr.Spec.Token {
+			case "valid-token":
+				tr.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:myns:mysa",
+						UID:      "myuid",
+						Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:myns", "system:authenticated"},
+						Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"openid"}},
+					},
+				}
+			case "invalid-token":
+				tr.Status = authenticationv1.TokenReviewStatus{Authenticated: false, Error: "invalid token"}
+			default:
+				return nil, errors.New("unexpected error")
+			}
+			return tr, nil
+		})
+	})
+
+	It("authenticates the user with a valid token", func() {
+		// given
+		authn := namespacelister.NewTokenReviewAuthenticator(reviewer, time.Minute)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add("Authorization", "Bearer valid-token")
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User).To(Equal(&user.DefaultInfo{
+			Name:   "system:serviceaccount:myns:mysa",
+			UID:    "myuid",
+			Groups: []string{"system:serviceaccounts", "system:serviceaccounts:myns", "system:authenticated"},
+			Extra:  map[string][]string{"scopes": {"openid"}},
+		}))
+	})
+
+	It("caches the TokenReview results", func() {
+		// given
+		authn := namespacelister.NewTokenReviewAuthenticator(reviewer, time.Minute)
+
+		// when
+		for range 3 {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Add("Authorization", "Bearer valid-token")
+			_, ok, err := authn.AuthenticateRequest(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+
+		// then
+		Expect(reviews).To(Equal(1))
+	})
+
+	DescribeTable("does not authenticate the user", func(header string, expectedErr bool) {
+		// given
+		authn := namespacelister.NewTokenReviewAuthenticator(reviewer, time.Minute)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Add("Authorization", header)
+		}
+
+		// when
+		_, ok, err := authn.AuthenticateRequest(r)
+
+		// then
+		Expect(ok).To(BeFalse())
+		if expectedErr {
+			Expect(err).To(HaveOccurred())
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+	},
+		Entry("no Authorization header", "", false),
+		Entry("invalid token", "Bearer invalid-token", true),
+		Entry("TokenReview error", "Bearer error-token", true),
+		Entry("non Bearer Authorization header", "Basic dXNlcjpwYXNzd29yZA==", false),
+	)
+})
+
+var _ = Describe("FrontProxyAuthenticator", func() {
+	const userHeader = "X-Email"
+
+	var (
+		ca    *x509.Certificate
+		caKey *ecdsa.PrivateKey
+		authn authenticator.Request
+	)
+
+	BeforeEach(func() {
+		ca, caKey = newCertificate("front-proxy-ca", nil, nil)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca)
+
+		authn = namespacelister.NewFrontProxyAuthenticator(
+			clientCAs,
+			[]string{"front-proxy"},
+			namespacelister.NewHeaderAuthenticator(namespacelister.UserHeaders{Username: userHeader}))
+	})
+
+	request := func(cert *x509.Certificate) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(userHeader, "myuser")
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		return r
+	}
+
+	It("authenticates the user when the front-proxy presents a valid certificate", func() {
+		// given
+		cert, _ := newCertificate("front-proxy", ca, caKey)
+
+		// when
+		rs, ok, err := authn.AuthenticateRequest(request(cert))
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal("myuser"))
+	})
+
+	It("does not authenticate the user when no certificate is presented", func() {
+		// when
+		_, ok, err := authn.AuthenticateRequest(request(nil))
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not authenticate the user when the certificate Common Name is not allowed", func() {
+		// given
+		cert, _ := newCertificate("not-the-front-proxy", ca, caKey)
+
+		// when
+		_, ok, err := authn.AuthenticateRequest(request(cert))
+
+		// then
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not authenticate the user when the certificate is not signed by the CA", func() {
+		// given
+		otherCA, otherCAKey := newCertificate("other-ca", nil, nil)
+		cert, _ := newCertificate("front-proxy", otherCA, otherCAKey)
+
+		// when
+		_, ok, err := authn.AuthenticateRequest(request(cert))
+
+		// then
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -3,15 +3,32 @@ package main
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// cachedObjects returns the resources kept in the cache
+func cachedObjects() []client.Object {
+	return []client.Object{
+		&corev1.Namespace{},
+		&rbacv1.RoleBinding{},
+		&rbacv1.ClusterRole{},
+		&rbacv1.ClusterRoleBinding{},
+		&rbacv1.Role{},
+	}
+}
+
 func BuildAndStartCache(ctx context.Context) (cache.Cache, error) {
 	cfg := ctrl.GetConfigOrDie()
 
@@ -22,13 +39,7 @@ func BuildAndStartCache(ctx context.Context) (cache.Cache, error) {
 	if err := rbacv1.AddToScheme(s); err != nil {
 		return nil, err
 	}
-	oo := []client.Object{
-		&corev1.Namespace{},
-		&rbacv1.RoleBinding{},
-		&rbacv1.ClusterRole{},
-		&rbacv1.ClusterRoleBinding{},
-		&rbacv1.Role{},
-	}
+	oo := cachedObjects()
 	c, err := cache.New(cfg, cache.Options{
 		Scheme: s,
 		ByObject: map[client.Object]cache.ByObject{
@@ -61,3 +72,127 @@ func BuildAndStartCache(ctx context.Context) (cache.Cache, error) {
 
 	return c, nil
 }
+
+// NotifyOnChange invokes notify every time one of the cached resources
+// is added, updated, or deleted, with the changed object: on updates, the old and the new one
+func NotifyOnChange(ctx context.Context, c cache.Cache, notify func(objs ...any)) error {
+	h := toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { notify(obj) },
+		UpdateFunc: func(oldObj, newObj any) { notify(oldObj, newObj) },
+		DeleteFunc: func(obj any) { notify(obj) },
+	}
+
+	for _, o := range cachedObjects() {
+		i, err := c.GetInformer(ctx, o)
+		if err != nil {
+			return fmt.Errorf("error getting informer for %T: %w", o, err)
+		}
+		if _, err := i.AddEventHandler(h); err != nil {
+			return fmt.Errorf("error adding event handler to informer for %T: %w", o, err)
+		}
+	}
+	return nil
+}
+
+// Generation counts the changes of the cached resources.
+// It can be used to detect whether results computed from the cache are outdated.
+type Generation struct {
+	v atomic.Uint64
+}
+
+// Inc records a change of the cached resources
+func (g *Generation) Inc() {
+	g.v.Add(1)
+}
+
+// Get returns the current generation
+func (g *Generation) Get() uint64 {
+	return g.v.Load()
+}
+
+// ChangeNotifier propagates the changes of the cached resources.
+// On each change, the hooks are invoked in registration order, then the generation is incremented,
+// and finally the subscribers the change is relevant to are notified: subscribers reading from the cache
+// observe the results of the hooks, e.g. an invalidated index.
+type ChangeNotifier struct {
+	Generation
+
+	// instance identifies the notifier among restarts and replicas,
+	// as their caches can differ
+	instance string
+
+	mu          sync.Mutex
+	hooks       []func(objs []any)
+	subscribers map[chan struct{}]ChangeFilter
+}
+
+// ChangeFilter returns whether the change of the objects is relevant to a subscriber
+type ChangeFilter func(objs []any) bool
+
+func NewChangeNotifier() *ChangeNotifier {
+	return &ChangeNotifier{
+		instance:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: map[chan struct{}]ChangeFilter{},
+	}
+}
+
+// OnChange registers a hook invoked on every change with the changed objects, if any
+func (n *ChangeNotifier) OnChange(hook func(objs []any)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.hooks = append(n.hooks, hook)
+}
+
+// Notify records a change of the cached resources.
+// The changed objects are optional, a change without objects is relevant to every subscriber.
+func (n *ChangeNotifier) Notify(objs ...any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, h := range n.hooks {
+		h(objs)
+	}
+	n.Inc()
+
+	// a pending notification already covers this change
+	for s, relevant := range n.subscribers {
+		if relevant != nil && len(objs) > 0 && !relevant(objs) {
+			continue
+		}
+		select {
+		case s <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving a value after one or more changes the filter deems relevant,
+// and the function to invoke to stop receiving them. A nil filter receives every change.
+func (n *ChangeNotifier) Subscribe(relevant ChangeFilter) (<-chan struct{}, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s := make(chan struct{}, 1)
+	n.subscribers[s] = relevant
+	return s, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subscribers, s)
+	}
+}
+
+// ResourceVersion returns an opaque version identifying the list of namespaces for this notifier:
+// it only changes when a namespace of the list is added, removed, or modified.
+// The namespaces' access annotation is included, as it is not reflected in their own version.
+func (n *ChangeNotifier) ResourceVersion(nn []corev1.Namespace) string {
+	h := fnv.New64a()
+	for _, ns := range nn {
+		for _, v := range []string{ns.Name, ns.ResourceVersion, ns.Annotations[AnnotationAccess]} {
+			_, _ = h.Write([]byte(v))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+	return fmt.Sprintf("%s.%x", n.instance, h.Sum64())
+}
@@ -1,13 +1,54 @@
 package main
 
+import "time"
+
 const (
-	EnvLogLevel       string = "LOG_LEVEL"
-	EnvHeaderUsername string = "HEADER_USERNAME"
-	EnvAddress        string = "ADDRESS"
+	EnvLogLevel            string = "LOG_LEVEL"
+	EnvHeaderUsername      string = "HEADER_USERNAME"
+	EnvHeaderUID           string = "HEADER_UID"
+	EnvHeaderGroups        string = "HEADER_GROUPS"
+	EnvHeaderExtraPrefixes string = "HEADER_EXTRA_PREFIXES"
+	EnvAddress             string = "ADDRESS"
+	EnvAuthMode            string = "AUTH_MODE"
+	EnvTokenReviewCacheTTL string = "TOKEN_REVIEW_CACHE_TTL"
+	EnvOIDCIssuerURL       string = "OIDC_ISSUER_URL"
+	EnvOIDCAudience        string = "OIDC_AUDIENCE"
+	EnvOIDCJWKS            string = "OIDC_JWKS"
+	EnvOIDCUsernameClaim   string = "OIDC_USERNAME_CLAIM"
+	EnvOIDCUsernamePrefix  string = "OIDC_USERNAME_PREFIX"
+	EnvOIDCGroupsClaim     string = "OIDC_GROUPS_CLAIM"
+	EnvOIDCGroupsPrefix    string = "OIDC_GROUPS_PREFIX"
+	EnvTLSCertFile         string = "TLS_CERT_FILE"
+	EnvTLSKeyFile          string = "TLS_KEY_FILE"
+	EnvAccessIndex         string = "ACCESS_INDEX"
+	EnvDecisionCache       string = "DECISION_CACHE"
+	EnvListWorkers         string = "LIST_WORKERS"
+	EnvListTimeout         string = "LIST_TIMEOUT"
+	EnvAccessResources     string = "ACCESS_RESOURCES"
+
+	EnvRequestHeaderClientCAFile string = "REQUESTHEADER_CLIENT_CA_FILE"
+	EnvRequestHeaderAllowedNames string = "REQUESTHEADER_ALLOWED_NAMES"
+
+	DefaultAddr                string        = ":8080"
+	DefaultHeaderUsername      string        = "X-Email"
+	DefaultAuthMode            string        = AuthModeHeader
+	DefaultTokenReviewCacheTTL time.Duration = 2 * time.Minute
+	DefaultListTimeout         time.Duration = 30 * time.Second
+	DefaultAccessResources     string        = "pods,deployments.apps,services,configmaps,secrets"
+
+	// AuthModeHeader trusts the user identity provided in the request headers
+	AuthModeHeader string = "header"
+	// AuthModeTokenReview validates the request's Bearer token with a TokenReview
+	AuthModeTokenReview string = "tokenreview"
+	// AuthModeOIDC validates the request's Bearer token as an OIDC ID token
+	AuthModeOIDC string = "oidc"
 
-	DefaultAddr           string = ":8080"
-	DefaultHeaderUsername string = "X-Email"
+	// AnnotationAccess is the annotation containing the verbs the user is allowed on the ACCESS_RESOURCES
+	AnnotationAccess string = "namespace-lister.io/access"
 
 	HttpContentType            string = "Content-Type"
 	HttpContentTypeApplication string = "application/json;charset=utf-8"
+	HttpContentTypeTable       string = "application/json;as=Table;v=v1;g=meta.k8s.io"
+	HttpContentTypeJSONLines   string = "application/jsonl;charset=utf-8"
+	HttpContentTypeCSV         string = "text/csv;charset=utf-8"
 )
@@ -2,13 +2,185 @@ package main
 
 import (
 	"cmp"
+	"fmt"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func getHeaderUsername() string {
 	return cmp.Or(os.Getenv(EnvHeaderUsername), DefaultHeaderUsername)
 }
 
+// getHeaderUID returns the header carrying the user's UID.
+// If not set, the UID is not read from requests.
+func getHeaderUID() string {
+	return os.Getenv(EnvHeaderUID)
+}
+
+// getHeaderGroups returns the header carrying the user's groups.
+// If not set, groups are not read from requests.
+func getHeaderGroups() string {
+	return os.Getenv(EnvHeaderGroups)
+}
+
+// getHeaderExtraPrefixes returns the comma-separated list of prefixes
+// of the headers carrying the user's extra attributes.
+// If not set, extra attributes are not read from requests.
+func getHeaderExtraPrefixes() []string {
+	return getList(EnvHeaderExtraPrefixes)
+}
+
+// getUserHeaders returns the headers carrying the user identity
+func getUserHeaders() UserHeaders {
+	return UserHeaders{
+		Username:      getHeaderUsername(),
+		UID:           getHeaderUID(),
+		Groups:        getHeaderGroups(),
+		ExtraPrefixes: getHeaderExtraPrefixes(),
+	}
+}
+
 func getAddress() string {
 	return cmp.Or(os.Getenv(EnvAddress), DefaultAddr)
 }
+
+// getAuthMode returns the mode used to authenticate requests
+func getAuthMode() string {
+	return cmp.Or(os.Getenv(EnvAuthMode), DefaultAuthMode)
+}
+
+// getTokenReviewCacheTTL returns the duration for which TokenReview results are cached
+func getTokenReviewCacheTTL() (time.Duration, error) {
+	return getDuration(EnvTokenReviewCacheTTL, DefaultTokenReviewCacheTTL)
+}
+
+// getOIDCOptions returns the configuration for validating OIDC ID tokens
+func getOIDCOptions() OIDCOptions {
+	return OIDCOptions{
+		IssuerURL:      os.Getenv(EnvOIDCIssuerURL),
+		Audience:       os.Getenv(EnvOIDCAudience),
+		UsernameClaim:  os.Getenv(EnvOIDCUsernameClaim),
+		UsernamePrefix: os.Getenv(EnvOIDCUsernamePrefix),
+		GroupsClaim:    os.Getenv(EnvOIDCGroupsClaim),
+		GroupsPrefix:   os.Getenv(EnvOIDCGroupsPrefix),
+	}
+}
+
+// getOIDCJWKS returns the location of the JWKS used to verify OIDC ID tokens,
+// either a file path or an HTTP(S) URL
+func getOIDCJWKS() string {
+	return os.Getenv(EnvOIDCJWKS)
+}
+
+// getTLSFiles returns the certificate and key files used for serving TLS.
+// If not set, the server does not use TLS.
+func getTLSFiles() (certFile, keyFile string) {
+	return os.Getenv(EnvTLSCertFile), os.Getenv(EnvTLSKeyFile)
+}
+
+// getRequestHeaderClientCAFile returns the CA file used to verify the front-proxy client certificate.
+// If not set, the front-proxy client certificate is not verified.
+func getRequestHeaderClientCAFile() string {
+	return os.Getenv(EnvRequestHeaderClientCAFile)
+}
+
+// getRequestHeaderAllowedNames returns the Common Names allowed in the front-proxy client certificate.
+// If not set, any Common Name is allowed.
+func getRequestHeaderAllowedNames() []string {
+	return getList(EnvRequestHeaderAllowedNames)
+}
+
+// getList reads a comma-separated list from the given environment variable
+func getList(env string) []string {
+	ll := []string{}
+	for _, v := range strings.Split(os.Getenv(env), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ll = append(ll, v)
+		}
+	}
+	return ll
+}
+
+// getAccessIndexEnabled returns whether list requests are answered from the AccessIndex.
+// Set to `false` to authorize the user on every namespace instead.
+func getAccessIndexEnabled() (bool, error) {
+	return getBool(EnvAccessIndex, true)
+}
+
+// getDecisionCacheEnabled returns whether the namespaces a user has access to are cached
+// until the cached resources change.
+func getDecisionCacheEnabled() (bool, error) {
+	return getBool(EnvDecisionCache, true)
+}
+
+// getAccessResources returns the resources included in the namespaces' access summary.
+// Resources are comma-separated and qualified by their API group, e.g. `deployments.apps`.
+func getAccessResources() ([]schema.GroupResource, error) {
+	ll := getList(EnvAccessResources)
+	if len(ll) == 0 {
+		ll = strings.Split(DefaultAccessResources, ",")
+	}
+
+	grr := make([]schema.GroupResource, 0, len(ll))
+	for _, v := range ll {
+		gr := schema.ParseGroupResource(v)
+		if err := (AccessCheck{Verb: "get", Resource: gr.Resource, APIGroup: gr.Group}).validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvAccessResources, err)
+		}
+		grr = append(grr, gr)
+	}
+	return grr, nil
+}
+
+// getBool reads a boolean from the given environment variable
+func getBool(env string, defaultValue bool) (bool, error) {
+	v := os.Getenv(env)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", env, err)
+	}
+	return b, nil
+}
+
+// getNamespaceListerOptions returns the configuration for evaluating the user access
+// on every namespace. Workers default to the number of usable CPUs.
+func getNamespaceListerOptions() (NamespaceListerOptions, error) {
+	opts := NamespaceListerOptions{Workers: runtime.GOMAXPROCS(0)}
+	if env := os.Getenv(EnvListWorkers); env != "" {
+		w, err := strconv.Atoi(env)
+		if err != nil || w < 1 {
+			return opts, fmt.Errorf("invalid %s: must be a positive integer", EnvListWorkers)
+		}
+		opts.Workers = w
+	}
+
+	t, err := getDuration(EnvListTimeout, DefaultListTimeout)
+	if err != nil {
+		return opts, err
+	}
+	opts.Timeout = t
+	return opts, nil
+}
+
+// getDuration reads a duration from the given environment variable
+func getDuration(env string, defaultValue time.Duration) (time.Duration, error) {
+	v := os.Getenv(env)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", env, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Explanation describes why a user is, or is not, allowed to get a namespace
+type Explanation struct {
+	Namespace string   `json:"namespace"`
+	User      string   `json:"user"`
+	Groups    []string `json:"groups,omitempty"`
+	Allowed   bool     `json:"allowed"`
+	// Reason is the reason reported by the RBAC authorizer
+	Reason string `json:"reason,omitempty"`
+	// GrantedBy is the binding and the rule granting the access, if allowed
+	GrantedBy *Grant `json:"grantedBy,omitempty"`
+	// EvaluatedBindings are the bindings the user is subject of, in evaluation order
+	EvaluatedBindings []BindingReference `json:"evaluatedBindings"`
+	// Errors are the errors encountered resolving the roles of the evaluated bindings
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Grant is a rule granting an access, and the binding it is granted by
+type Grant struct {
+	Binding BindingReference  `json:"binding"`
+	Rule    rbacv1.PolicyRule `json:"rule"`
+}
+
+// BindingReference identifies a RoleBinding or a ClusterRoleBinding and the role it refers to
+type BindingReference struct {
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	RoleRef   rbacv1.RoleRef `json:"roleRef"`
+}
+
+func (b BindingReference) String() string {
+	if b.Namespace == "" {
+		return fmt.Sprintf("%s %q", b.Kind, b.Name)
+	}
+	return fmt.Sprintf("%s %q in namespace %q", b.Kind, b.Name, b.Namespace)
+}
+
+// rbacBinding is a RoleBinding or a ClusterRoleBinding
+type rbacBinding struct {
+	BindingReference
+
+	subjects []rbacv1.Subject
+}
+
+// appliesTo returns whether the user, or one of its groups, is subject of the binding
+func (b rbacBinding) appliesTo(subjects sets.Set[indexSubject]) bool {
+	for _, s := range b.subjects {
+		if is, ok := newIndexSubject(s, b.Namespace); ok && subjects.Has(is) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explainer explains the decisions of the RBAC authorizer on namespaces
+type Explainer struct {
+	reader client.Reader
+	l      *slog.Logger
+}
+
+func NewExplainer(reader client.Reader, l *slog.Logger) *Explainer {
+	return &Explainer{
+		reader: reader,
+		l:      l,
+	}
+}
+
+// Explain evaluates, as the RBAC authorizer does, the bindings granting the user `get` access on the namespace
+func (e *Explainer) Explain(ctx context.Context, namespace string, ui user.Info) (*Explanation, error) {
+	if _, err := getNamespace(ctx, e.reader, namespace); err != nil {
+		return nil, err
+	}
+
+	aur := NewCRAuthRetriever(ctx, e.reader, e.l)
+	attrs := DefaultAccessCheck.Attributes(namespace)
+	attrs.User = ui
+	d, reason, err := rbac.New(aur, aur, aur, aur).Authorize(ctx, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := listBindings(aur, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &Explanation{
+		Namespace:         namespace,
+		User:              ui.GetName(),
+		Groups:            ui.GetGroups(),
+		Allowed:           d == authorizer.DecisionAllow,
+		Reason:            reason,
+		EvaluatedBindings: []BindingReference{},
+	}
+	resolver := NewRuleResolver(ctx, e.reader, e.l)
+	subjects := sets.New(userIndexSubjects(ui)...)
+	for _, b := range bb {
+		if !b.appliesTo(subjects) {
+			continue
+		}
+		x.EvaluatedBindings = append(x.EvaluatedBindings, b.BindingReference)
+
+		rr, err := resolver.GetRoleReferenceRules(b.RoleRef, b.Namespace)
+		if err != nil {
+			x.Errors = append(x.Errors, fmt.Sprintf("%s: %v", b, err))
+			continue
+		}
+		for _, r := range rr {
+			if rbac.RuleAllows(attrs, &r) {
+				x.GrantedBy = &Grant{Binding: b.BindingReference, Rule: r}
+				return x, nil
+			}
+		}
+	}
+	return x, nil
+}
+
+// listBindings returns the ClusterRoleBindings and the RoleBindings in the namespace,
+// in the order they are evaluated by the RBAC authorizer
+func listBindings(aur *CRAuthRetriever, namespace string) ([]rbacBinding, error) {
+	crbb, err := aur.ListClusterRoleBindings()
+	if err != nil {
+		return nil, err
+	}
+	rbb, err := aur.ListRoleBindings(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := make([]rbacBinding, 0, len(crbb)+len(rbb))
+	for _, crb := range crbb {
+		bb = append(bb, rbacBinding{
+			BindingReference: BindingReference{Kind: "ClusterRoleBinding", Name: crb.Name, RoleRef: crb.RoleRef},
+			subjects:         crb.Subjects,
+		})
+	}
+	for _, rb := range rbb {
+		bb = append(bb, rbacBinding{
+			BindingReference: BindingReference{Kind: "RoleBinding", Namespace: rb.Namespace, Name: rb.Name, RoleRef: rb.RoleRef},
+			subjects:         rb.Subjects,
+		})
+	}
+	return bb, nil
+}
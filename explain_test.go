@@ -0,0 +1,138 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// cacheReader reports missing objects on their Kind, as the informer cache does
+type cacheReader struct {
+	client.Reader
+}
+
+func (r *cacheReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := r.Reader.Get(ctx, key, obj, opts...)
+	if kerrors.IsNotFound(err) {
+		return kerrors.NewNotFound(schema.GroupResource{Resource: reflect.TypeOf(obj).Elem().Name()}, key.Name)
+	}
+	return err
+}
+
+var _ = Describe("Explainer", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+		explainer *namespacelister.Explainer
+	)
+
+	BeforeEach(func() {
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "basic"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"pods"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules: []rbacv1.PolicyRule{
+						{APIGroups: []string{""}, Verbs: []string{"list"}, Resources: []string{"pods"}},
+						{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}},
+					},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "basic:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "basic"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:mygroup", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "mygroup"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "missing"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:other", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "other"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		explainer = namespacelister.NewExplainer(&cacheReader{Reader: reader}, logger)
+	})
+
+	It("explains which binding and rule grant the access", func() {
+		// when
+		x, err := explainer.Explain(ctx, "myns-1", &user.DefaultInfo{Name: "user", Groups: []string{"mygroup"}})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(x.Allowed).To(BeTrue())
+		Expect(x.Reason).To(ContainSubstring("ns-get:mygroup"))
+		grantingBinding := namespacelister.BindingReference{
+			Kind:      "RoleBinding",
+			Namespace: "myns-1",
+			Name:      "ns-get:mygroup",
+			RoleRef:   rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+		}
+		Expect(x.GrantedBy).To(Equal(&namespacelister.Grant{
+			Binding: grantingBinding,
+			Rule:    rbacv1.PolicyRule{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}},
+		}))
+		Expect(x.EvaluatedBindings).To(HaveLen(2))
+		Expect(x.EvaluatedBindings[0].Name).To(Equal("basic:user"))
+		Expect(x.EvaluatedBindings[1]).To(Equal(grantingBinding))
+		Expect(x.Errors).To(BeEmpty())
+	})
+
+	It("lists the evaluated bindings and the resolution errors if the access is not granted", func() {
+		// when
+		x, err := explainer.Explain(ctx, "myns-2", &user.DefaultInfo{Name: "user", Groups: []string{"mygroup"}})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(x.Allowed).To(BeFalse())
+		Expect(x.GrantedBy).To(BeNil())
+		Expect(x.EvaluatedBindings).To(HaveLen(1))
+		Expect(x.EvaluatedBindings[0].Name).To(Equal("missing:user"))
+		Expect(x.Errors).To(HaveLen(1))
+		Expect(x.Errors[0]).To(ContainSubstring("missing:user"))
+	})
+
+	It("returns NotFound if the namespace does not exist", func() {
+		// when
+		_, err := explainer.Explain(ctx, "notfound", &user.DefaultInfo{Name: "user"})
+
+		// then
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		Expect(err).To(MatchError(`namespaces "notfound" not found`))
+		Expect(err.(kerrors.APIStatus).Status().Details.Kind).To(Equal("namespaces"))
+	})
+})
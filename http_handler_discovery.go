@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/kube-openapi/pkg/handler"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	patternGetAPI        string = "GET /api"
+	patternGetAPIV1      string = "GET /api/v1"
+	patternGetAPIs       string = "GET /apis"
+	patternGetVersion    string = "GET /version"
+	patternOpenAPIV2     string = "/openapi/v2"
+	patternGetOpenAPIV3  string = "GET /openapi/v3"
+	patternGetOpenAPIV3G string = "GET /openapi/v3/"
+
+	// openAPIV3GroupVersion is the path of the core/v1 document in the OpenAPI v3 discovery
+	openAPIV3GroupVersion string = "api/v1"
+
+	openAPITitle string = "Namespace-Lister"
+)
+
+// registerDiscoveryHandlers serves the Kubernetes discovery endpoints,
+// so that clients like kubectl and client-go can target the Namespace-Lister directly.
+// The only resource exposed is namespaces, watch is advertised only if watchable is set.
+// As kube-apiserver does with the `system:discovery` and `system:public-info-viewer` ClusterRoles,
+// only the version is public, the other endpoints are reserved to authenticated users.
+func registerDiscoveryHandlers(s *NamespaceListerServer, watchable bool) {
+	l, v := s.logger, serverVersion()
+
+	verbs := metav1.Verbs{"get", "list"}
+	if watchable {
+		verbs = append(verbs, "watch")
+	}
+	resources := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: corev1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{{
+			Name:         "namespaces",
+			SingularName: "namespace",
+			Namespaced:   false,
+			Kind:         "Namespace",
+			Verbs:        verbs,
+			ShortNames:   []string{"ns"},
+		}},
+	}
+
+	s.Handle(patternGetAPI, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(l, w, &metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{corev1.SchemeGroupVersion.Version},
+			ServerAddressByClientCIDRs: []metav1.ServerAddressByClientCIDR{
+				{ClientCIDR: "0.0.0.0/0", ServerAddress: r.Host},
+			},
+		})
+	}))
+	s.Handle(patternGetAPIV1, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(l, w, resources)
+	}))
+	// client-go requires the API groups to be listed, there are none
+	s.Handle(patternGetAPIs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(l, w, &metav1.APIGroupList{
+			TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+			Groups:   []metav1.APIGroup{},
+		})
+	}))
+	s.mux.Handle(patternGetVersion, addLogMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(l, w, &v)
+	})))
+
+	// the OpenAPI documents are served in JSON and Protobuf as kube-apiserver does
+	info := &spec.Info{InfoProps: spec.InfoProps{Title: openAPITitle, Version: v.GitVersion}}
+	v2 := handler.NewOpenAPIService(&spec.Swagger{SwaggerProps: spec.SwaggerProps{
+		Swagger: "2.0",
+		Info:    info,
+		Paths:   &spec.Paths{Paths: map[string]spec.PathItem{}},
+	}})
+	v2.RegisterOpenAPIVersionedService(patternOpenAPIV2, s)
+
+	v3 := handler3.NewOpenAPIService()
+	v3.UpdateGroupVersion(openAPIV3GroupVersion, &spec3.OpenAPI{
+		Version: "3.0.0",
+		Info:    info,
+		Paths:   &spec3.Paths{Paths: map[string]*spec3.Path{}},
+	})
+	s.Handle(patternGetOpenAPIV3, http.HandlerFunc(v3.HandleDiscovery))
+	s.Handle(patternGetOpenAPIV3G, http.HandlerFunc(v3.HandleGroupVersion))
+}
+
+// serverVersion returns the version of the Namespace-Lister read from its build information
+func serverVersion() version.Info {
+	v := version.Info{
+		GitVersion: "v0.0.0-unknown",
+		GoVersion:  runtime.Version(),
+		Compiler:   runtime.Compiler,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		v.GitVersion = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.GitCommit = s.Value
+		case "vcs.time":
+			v.BuildDate = s.Value
+		case "vcs.modified":
+			v.GitTreeState = "clean"
+			if s.Value == "true" {
+				v.GitTreeState = "dirty"
+			}
+		}
+	}
+	return v
+}
+
+// writeJSON replies with obj encoded in JSON
+func writeJSON(l *slog.Logger, w http.ResponseWriter, obj any) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		writeStatus(l, w, err)
+		return
+	}
+
+	w.Header().Set(HttpContentType, HttpContentTypeApplication)
+	if _, err := w.Write(b); err != nil {
+		l.Error("error writing reply", "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package main_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("Discovery", func() {
+	var (
+		log  *slog.Logger
+		auth AuthenticatorMock
+	)
+
+	// newDiscoveryClient starts the server and returns a discovery client targeting it
+	newDiscoveryClient := func(changes *namespacelister.ChangeNotifier) (*discovery.DiscoveryClient, *httptest.Server) {
+		s := namespacelister.NewServer(log, nil, nil, changes, auth)
+		srv := httptest.NewServer(s.Handler)
+		DeferCleanup(srv.Close)
+		return discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: srv.URL}), srv
+	}
+
+	BeforeEach(func() {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+		auth = AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "myuser"}}, true, nil
+		})
+	})
+
+	DescribeTable("exposes the namespaces", func(changes *namespacelister.ChangeNotifier, expectedVerbs metav1.Verbs) {
+		// given
+		dc, _ := newDiscoveryClient(changes)
+
+		// when
+		gg, rr, err := dc.ServerGroupsAndResources()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gg).To(HaveLen(1))
+		Expect(gg[0].Name).To(BeEmpty())
+		Expect(gg[0].PreferredVersion.Version).To(Equal("v1"))
+		Expect(rr).To(HaveLen(1))
+		Expect(rr[0].GroupVersion).To(Equal("v1"))
+		Expect(rr[0].APIResources).To(HaveLen(1))
+		r := rr[0].APIResources[0]
+		Expect(r.Name).To(Equal("namespaces"))
+		Expect(r.Kind).To(Equal("Namespace"))
+		Expect(r.Namespaced).To(BeFalse())
+		Expect(r.Verbs).To(Equal(expectedVerbs))
+	},
+		Entry("with watch", namespacelister.NewChangeNotifier(), metav1.Verbs{"get", "list", "watch"}),
+		Entry("without watch", nil, metav1.Verbs{"get", "list"}),
+	)
+
+	It("serves the version to unauthenticated users", func() {
+		// given
+		auth = AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return nil, false, nil
+		})
+		dc, _ := newDiscoveryClient(nil)
+
+		// when
+		v, err := dc.ServerVersion()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.GitVersion).NotTo(BeEmpty())
+		Expect(v.GoVersion).To(Equal(runtime.Version()))
+	})
+
+	It("serves the OpenAPI v2 document", func() {
+		// given
+		dc, srv := newDiscoveryClient(nil)
+
+		// when
+		d, err := dc.OpenAPISchema()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Swagger).To(Equal("2.0"))
+		Expect(d.Info.Title).To(Equal("Namespace-Lister"))
+
+		// when
+		rsp, err := srv.Client().Get(srv.URL + "/openapi/v2")
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(rsp.Header.Get(namespacelister.HttpContentType)).To(Equal("application/json"))
+		doc := map[string]any{}
+		Expect(json.NewDecoder(rsp.Body).Decode(&doc)).To(Succeed())
+		Expect(doc).To(HaveKeyWithValue("swagger", "2.0"))
+	})
+
+	It("serves the OpenAPI v3 documents", func() {
+		// given
+		dc, _ := newDiscoveryClient(nil)
+
+		// when
+		pp, err := dc.OpenAPIV3().Paths()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp).To(HaveLen(1))
+		Expect(pp).To(HaveKey("api/v1"))
+
+		// when
+		b, err := pp["api/v1"].Schema("application/json")
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		doc := map[string]any{}
+		Expect(json.Unmarshal(b, &doc)).To(Succeed())
+		Expect(doc).To(HaveKeyWithValue("openapi", "3.0.0"))
+	})
+
+	DescribeTable("requires authentication", func(path string) {
+		// given
+		auth = AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return nil, false, nil
+		})
+		_, srv := newDiscoveryClient(nil)
+
+		// when
+		rsp, err := srv.Client().Get(srv.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusUnauthorized))
+	},
+		Entry("core API versions", "/api"),
+		Entry("core v1 resources", "/api/v1"),
+		Entry("API groups", "/apis"),
+		Entry("OpenAPI v2", "/openapi/v2"),
+		Entry("OpenAPI v3 discovery", "/openapi/v3"),
+		Entry("OpenAPI v3 document", "/openapi/v3/api/v1"),
+	)
+
+	It("returns a NotFound Status for the other API groups", func() {
+		// given
+		_, srv := newDiscoveryClient(nil)
+
+		// when
+		rsp, err := srv.Client().Get(srv.URL + "/apis/apps/v1")
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
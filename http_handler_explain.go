@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	queryParamUser      string = "user"
+	queryParamUserGroup string = "group"
+)
+
+var _ http.Handler = &ExplainHandler{}
+
+// ExplainHandler explains why a user is, or is not, allowed to get a namespace.
+// The user and its groups are read from the `user` and `group` query parameters.
+type ExplainHandler struct {
+	log       *slog.Logger
+	explainer *Explainer
+}
+
+func NewExplainHandler(log *slog.Logger, explainer *Explainer) http.Handler {
+	return &ExplainHandler{
+		log:       log,
+		explainer: explainer,
+	}
+}
+
+func (h *ExplainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	username := q.Get(queryParamUser)
+	if username == "" {
+		writeStatus(h.log, w, kerrors.NewBadRequest("the user query parameter is required"))
+		return
+	}
+
+	// as for authenticated requests, ServiceAccount and authenticated groups are added
+	ui := newUserInfo(username, "", q[queryParamUserGroup], nil)
+	name := r.PathValue("name")
+	h.log.Info("received explain request", "namespace", name, userLogAttr(ui))
+
+	x, err := h.explainer.Explain(r.Context(), name, ui)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	b, err := json.Marshal(x)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	w.Header().Add(HttpContentType, HttpContentTypeApplication)
+	if _, err := w.Write(b); err != nil {
+		h.log.Error("error writing reply", "error", err)
+	}
+}
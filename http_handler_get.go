@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ http.Handler = &GetNamespaceHandler{}
+
+// GetNamespaceHandler returns the cached namespace if the user is allowed to get it.
+// As kube-apiserver does, the user is authorized before looking up the namespace:
+// users not allowed to get it receive 403 Forbidden whether it exists or not.
+type GetNamespaceHandler struct {
+	log    *slog.Logger
+	reader client.Reader
+	authz  authorizer.Authorizer
+}
+
+func NewGetNamespaceHandler(log *slog.Logger, reader client.Reader, authz authorizer.Authorizer) http.Handler {
+	return &GetNamespaceHandler{
+		log:    log,
+		reader: reader,
+		authz:  authz,
+	}
+}
+
+func (h *GetNamespaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the user is authenticated by the authentication middleware
+	ui, ok := request.UserFrom(r.Context())
+	if !ok {
+		writeStatus(h.log, w, kerrors.NewUnauthorized(http.StatusText(http.StatusUnauthorized)))
+		return
+	}
+
+	name := r.PathValue("name")
+	h.log.Info("received get request", "namespace", name, userLogAttr(ui))
+
+	rep, err := negotiateOutput(r, false, KindTable, KindPartialObjectMetadata)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	attrs := DefaultAccessCheck.Attributes(name)
+	attrs.User = ui
+	d, _, err := h.authz.Authorize(r.Context(), attrs)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+	if d != authorizer.DecisionAllow {
+		writeStatus(h.log, w, kerrors.NewForbidden(corev1.Resource("namespaces"), name,
+			fmt.Errorf("User %q cannot get resource \"namespaces\" in API group \"\" in the namespace %q", ui.GetName(), name)))
+		return
+	}
+
+	ns, err := getNamespace(r.Context(), h.reader, name)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	obj, err := rep.object(ns)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+	b := bytes.Buffer{}
+	if err := rep.encode(&b, obj); err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	w.Header().Add(HttpContentType, rep.contentType())
+	if _, err := w.Write(b.Bytes()); err != nil {
+		h.log.Error("error writing reply", "error", err)
+	}
+}
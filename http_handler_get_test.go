@@ -0,0 +1,127 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("HttpHandlerGet", func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}}},
+				},
+			}},
+			&rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:admin"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "admin"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+
+		// the handler reads the namespace's name from the path
+		mux := http.NewServeMux()
+		mux.Handle("GET /api/v1/namespaces/{name}", namespacelister.NewGetNamespaceHandler(log, &cacheReader{Reader: reader}, namespacelister.NewAuthorizer(context.TODO(), reader, log)))
+		handler = mux
+	})
+
+	get := func(username, name string) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/"+name, nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: username}))
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	It("returns the namespace if the user is allowed to get it", func() {
+		// when
+		rs := get("user", "myns-1")
+
+		// then
+		Expect(rs.StatusCode).To(Equal(http.StatusOK))
+		Expect(rs.Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		ns := corev1.Namespace{}
+		Expect(json.NewDecoder(rs.Body).Decode(&ns)).To(Succeed())
+		Expect(ns.Kind).To(Equal("Namespace"))
+		Expect(ns.APIVersion).To(Equal("v1"))
+		Expect(ns.Name).To(Equal("myns-1"))
+	})
+
+	It("returns the namespace as a Table when accepted", func() {
+		// given
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/myns-1", nil)
+		r.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io,application/json")
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "user"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeTable))
+		t := metav1.Table{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&t)).To(Succeed())
+		Expect(t.Kind).To(Equal("Table"))
+		Expect(t.Rows).To(HaveLen(1))
+		Expect(t.Rows[0].Cells[0]).To(Equal("myns-1"))
+	})
+
+	DescribeTable("returns a Status if the namespace can not be returned", func(username, name string, expectedCode int, expectedReason metav1.StatusReason, expectedMessage string) {
+		// when
+		rs := get(username, name)
+
+		// then
+		Expect(rs.StatusCode).To(Equal(expectedCode))
+		Expect(rs.Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		s := metav1.Status{}
+		Expect(json.NewDecoder(rs.Body).Decode(&s)).To(Succeed())
+		Expect(s.Kind).To(Equal("Status"))
+		Expect(s.Status).To(Equal(metav1.StatusFailure))
+		Expect(s.Reason).To(Equal(expectedReason))
+		Expect(s.Message).To(Equal(expectedMessage))
+		Expect(s.Details.Name).To(Equal(name))
+		Expect(s.Details.Kind).To(Equal("namespaces"))
+	},
+		Entry("forbidden", "user", "myns-2", http.StatusForbidden, metav1.StatusReasonForbidden,
+			`namespaces "myns-2" is forbidden: User "user" cannot get resource "namespaces" in API group "" in the namespace "myns-2"`),
+		Entry("forbidden even if it does not exist", "user", "notfound", http.StatusForbidden, metav1.StatusReasonForbidden,
+			`namespaces "notfound" is forbidden: User "user" cannot get resource "namespaces" in API group "" in the namespace "notfound"`),
+		Entry("not found", "admin", "notfound", http.StatusNotFound, metav1.StatusReasonNotFound,
+			`namespaces "notfound" not found`),
+	)
+})
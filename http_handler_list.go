@@ -1,65 +1,131 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
+	"bytes"
 	"log/slog"
 	"net/http"
 
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ http.Handler = &ListNamespacesHandler{}
 
 type ListNamespacesHandler struct {
-	log        *slog.Logger
-	lister     NamespaceLister
-	userHeader string
+	log    *slog.Logger
+	lister NamespaceLister
+	// changes notifies the changes of the cached resources, watch requests are rejected if nil
+	changes *ChangeNotifier
+	// snapshots keeps the lists whose pages are being read
+	snapshots *listSnapshots
 }
 
-func NewListNamespacesHandler(log *slog.Logger, lister NamespaceLister, userHeader string) http.Handler {
+// NewListNamespacesHandler builds the handler of the list and watch requests.
+// The namespaces of the pages following the first one are read from the reader.
+func NewListNamespacesHandler(log *slog.Logger, reader client.Reader, lister NamespaceLister, changes *ChangeNotifier) http.Handler {
 	return &ListNamespacesHandler{
-		log:        log,
-		lister:     lister,
-		userHeader: userHeader,
+		log:       log,
+		lister:    lister,
+		changes:   changes,
+		snapshots: newListSnapshots(reader, snapshotTTL),
 	}
 }
 
 func (h *ListNamespacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.log.Info("received list request")
-	// retrieve projects as the user
-	nn, err := h.lister.ListNamespaces(r.Context(), r.Header.Get(h.userHeader))
+	// the user is authenticated by the authentication middleware
+	ui, ok := request.UserFrom(r.Context())
+	if !ok {
+		writeStatus(h.log, w, kerrors.NewUnauthorized(http.StatusText(http.StatusUnauthorized)))
+		return
+	}
+	h.log.Info("received list request", userLogAttr(ui))
+
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	wopts, err := parseWatchOptions(r.URL.Query())
 	if err != nil {
-		serr := &kerrors.StatusError{}
-		if errors.As(err, &serr) {
-			w.WriteHeader(int(serr.Status().Code))
-			h.write(w, []byte(serr.Error()))
+		writeStatus(h.log, w, err)
+		return
+	}
+	if wopts.Watch {
+		rep, err := negotiateOutput(r, true, KindTable, KindPartialObjectMetadata)
+		if err != nil {
+			writeStatus(h.log, w, err)
 			return
 		}
+		h.watch(w, r, ui, opts, wopts, rep)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		h.write(w, []byte(err.Error()))
+	rep, err := negotiateOutput(r, false, KindTable, KindPartialObjectMetadataList)
+	if err != nil {
+		writeStatus(h.log, w, err)
 		return
 	}
 
-	// build response
-	// for PoC limited to JSON
-	b, err := json.Marshal(nn)
+	popts, err := parsePageOptions(r.URL.Query())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		h.write(w, []byte(err.Error()))
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	// the following pages are served from the snapshot of the first one
+	if popts.Continue != "" {
+		nn, err := h.snapshots.next(r.Context(), ui, opts, popts)
+		if err != nil {
+			writeStatus(h.log, w, err)
+			return
+		}
+		h.writeList(w, nn, rep)
 		return
 	}
 
-	w.Header().Add(HttpContentType, HttpContentTypeApplication)
-	h.write(w, b)
+	// retrieve projects as the user
+	nn, err := h.lister.ListNamespaces(r.Context(), ui, opts)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	// the list may be shared with concurrent requests, only its copy is versioned
+	if h.changes != nil {
+		versioned := *nn
+		versioned.ResourceVersion = h.changes.ResourceVersion(nn.Items)
+		nn = &versioned
+	}
+
+	nn, err = h.snapshots.first(ui, opts, nn, popts.Limit)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+	h.writeList(w, nn, rep)
 }
 
-func (h *ListNamespacesHandler) write(w http.ResponseWriter, data []byte) bool {
-	if _, err := w.Write(data); err != nil {
+// writeList replies with the list of namespaces in the negotiated representation
+func (h *ListNamespacesHandler) writeList(w http.ResponseWriter, nn *corev1.NamespaceList, rep *representation) {
+	obj, err := rep.list(nn)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	// build response
+	b := bytes.Buffer{}
+	if err := rep.encode(&b, obj); err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	w.Header().Add(HttpContentType, rep.contentType())
+	// the status is already sent, failures can only be logged
+	if _, err := w.Write(b.Bytes()); err != nil {
 		h.log.Error("error writing reply", "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return false
 	}
-	return true
 }
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	namespacelister "github.com/konflux-ci/namespace-lister"
 	. "github.com/onsi/ginkgo/v2"
@@ -16,17 +18,35 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
-type NamespaceListerMock func(ctx context.Context, username string) (*corev1.NamespaceList, error)
+type NamespaceListerMock func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error)
 
-func (m NamespaceListerMock) ListNamespaces(ctx context.Context, username string) (*corev1.NamespaceList, error) {
-	return m(ctx, username)
+func (m NamespaceListerMock) ListNamespaces(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+	return m(ctx, user, opts)
 }
 
-var _ = Describe("HttpHandlerList", func() {
-	const userHeader = "X-Email"
+// metaCodecs decode the meta.k8s.io representations of the namespaces
+var metaCodecs = func() serializer.CodecFactory {
+	s := runtime.NewScheme()
+	utilruntime.Must(metav1.AddMetaToScheme(s))
+	metav1.AddToGroupVersion(s, schema.GroupVersion{Version: "v1"})
+	return serializer.NewCodecFactory(s)
+}()
+
+var defaultListOptions = namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck}
 
+var _ = Describe("HttpHandlerList", func() {
 	var (
 		log *slog.Logger
 	)
@@ -41,14 +61,14 @@ var _ = Describe("HttpHandlerList", func() {
 		if err != nil {
 			panic(err)
 		}
-		lister := NamespaceListerMock(func(ctx context.Context, username string) (*corev1.NamespaceList, error) {
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
 			return &expected, nil
 		})
-		handler := namespacelister.NewListNamespacesHandler(log, lister, userHeader)
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/", nil)
-		r.Header.Add(userHeader, "myuser")
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
 
 		// when
 		handler.ServeHTTP(w, r)
@@ -73,16 +93,16 @@ var _ = Describe("HttpHandlerList", func() {
 		}),
 	)
 
-	DescribeTable("returns an error when lister returns an error", func(expectedErr error, expectedResponseStatus int) {
+	DescribeTable("returns a Status when lister returns an error", func(expectedErr error, expectedResponseStatus int, expectedReason metav1.StatusReason, expectedMessage string) {
 		// given
-		lister := NamespaceListerMock(func(ctx context.Context, username string) (*corev1.NamespaceList, error) {
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
 			return nil, expectedErr
 		})
-		handler := namespacelister.NewListNamespacesHandler(log, lister, userHeader)
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/", nil)
-		r.Header.Add(userHeader, "myuser")
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
 
 		// when
 		handler.ServeHTTP(w, r)
@@ -90,12 +110,506 @@ var _ = Describe("HttpHandlerList", func() {
 		// then
 		Expect(w.Result()).NotTo(BeNil())
 		Expect(w.Result().StatusCode).To(Equal(expectedResponseStatus))
-		// Expect(w.Result().Header.Get(HttpContentType)).To(Equal(HttpContentTypeApplication))
-		wb, err := io.ReadAll(w.Result().Body)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(wb).To(BeEquivalentTo(expectedErr.Error()))
+		Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		s := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&s)).To(Succeed())
+		Expect(s.Kind).To(Equal("Status"))
+		Expect(s.APIVersion).To(Equal("v1"))
+		Expect(s.Status).To(Equal(metav1.StatusFailure))
+		Expect(s.Code).To(BeEquivalentTo(expectedResponseStatus))
+		Expect(s.Reason).To(Equal(expectedReason))
+		Expect(s.Message).To(Equal(expectedMessage))
+	},
+		Entry("unhandled error", fmt.Errorf("unhandled error"), http.StatusInternalServerError,
+			metav1.StatusReasonInternalError, "Internal error occurred: unhandled error"),
+		Entry("handled error", kerrors.NewTimeoutError("timed-out", 200), http.StatusGatewayTimeout,
+			metav1.StatusReasonTimeout, "Timeout: timed-out"),
+	)
+
+	DescribeTable("reads the access check from the query parameters", func(query string, expected namespacelister.AccessCheck) {
+		// given
+		var received namespacelister.ListOptions
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			received = opts
+			return &corev1.NamespaceList{}, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(received.Access).To(Equal(expected))
+	},
+		Entry("default", "", namespacelister.DefaultAccessCheck),
+		Entry("verb, resource and group", "verb=create&resource=deployments&group=apps",
+			namespacelister.AccessCheck{Verb: "create", Resource: "deployments", APIGroup: "apps"}),
+		Entry("core resource", "verb=list&resource=secrets",
+			namespacelister.AccessCheck{Verb: "list", Resource: "secrets"}),
+		Entry("subresource", "verb=create&resource=pods/exec",
+			namespacelister.AccessCheck{Verb: "create", Resource: "pods/exec"}),
+		Entry("verb on namespaces", "verb=delete",
+			namespacelister.AccessCheck{Verb: "delete", Resource: "namespaces"}),
+	)
+
+	It("reads the role and the access summary request from the query parameters", func() {
+		// given
+		var received namespacelister.ListOptions
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			received = opts
+			return &corev1.NamespaceList{}, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/?role=system:admin&includeAccess=true", nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(received).To(Equal(namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, Role: "system:admin", IncludeAccess: true}))
+	})
+
+	DescribeTable("reads the selectors from the query parameters", func(query string, expectedLabelSelector, expectedFieldSelector string) {
+		// given
+		var received namespacelister.ListOptions
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			received = opts
+			return &corev1.NamespaceList{}, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(received.LabelSelector).To(Equal(expectedLabelSelector))
+		Expect(received.FieldSelector).To(Equal(expectedFieldSelector))
 	},
-		Entry("unhandled error", fmt.Errorf("unhandled error"), http.StatusInternalServerError),
-		Entry("handled error", kerrors.NewTimeoutError("timed-out", 200), http.StatusGatewayTimeout),
+		Entry("none", "", "", ""),
+		Entry("label selector", "labelSelector=konflux.ci/type%3Duser", "konflux.ci/type=user", ""),
+		Entry("label selector in canonical form", "labelSelector=b,a%20in%20(y,x)", "a in (x,y),b", ""),
+		Entry("field selectors", "fieldSelector=metadata.name!%3Dmyns,status.phase%3DActive", "", "metadata.name!=myns,status.phase=Active"),
 	)
+
+	DescribeTable("rejects unsafe access checks with a BadRequest Status", func(query string) {
+		// given
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			Fail("lister should not be invoked")
+			return nil, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		s := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&s)).To(Succeed())
+		Expect(s.Kind).To(Equal("Status"))
+		Expect(s.Reason).To(Equal(metav1.StatusReasonBadRequest))
+		Expect(s.Message).NotTo(BeEmpty())
+	},
+		Entry("unknown verb", "verb=escalate&resource=roles&group=rbac.authorization.k8s.io"),
+		Entry("impersonation", "verb=impersonate&resource=users"),
+		Entry("wildcard verb", "verb=*&resource=pods"),
+		Entry("wildcard resource", "verb=get&resource=*"),
+		Entry("wildcard group", "verb=get&resource=deployments&group=*"),
+		Entry("invalid resource", "verb=get&resource=Pods"),
+		Entry("cluster-scoped resource", "verb=get&resource=nodes"),
+		Entry("cluster-scoped verb on namespaces", "verb=list&resource=namespaces"),
+		Entry("invalid role", "role=my/role"),
+		Entry("invalid includeAccess", "includeAccess=maybe"),
+		Entry("invalid label selector", "labelSelector=a%3D%3D%3Db"),
+		Entry("invalid field selector", "fieldSelector=metadata.name"),
+		Entry("unsupported field", "fieldSelector=metadata.namespace%3Dmyns"),
+	)
+
+	Context("when paginating", func() {
+		var (
+			calls   int
+			reader  client.Client
+			handler http.Handler
+		)
+
+		// get requests the list as the given user and decodes the reply
+		get := func(username, query string) (int, corev1.NamespaceList) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: username}))
+			handler.ServeHTTP(w, r)
+
+			nn := corev1.NamespaceList{}
+			if w.Result().StatusCode == http.StatusOK {
+				ExpectWithOffset(1, json.NewDecoder(w.Result().Body).Decode(&nn)).To(Succeed())
+			}
+			return w.Result().StatusCode, nn
+		}
+
+		names := func(nn corev1.NamespaceList) []string {
+			n := []string{}
+			for _, ns := range nn.Items {
+				n = append(n, ns.Name)
+			}
+			return n
+		}
+
+		BeforeEach(func() {
+			calls = 0
+			nn := corev1.NamespaceList{}
+			for _, n := range []string{"ns-1", "ns-2", "ns-3", "ns-4", "ns-5"} {
+				nn.Items = append(nn.Items, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: n}})
+			}
+			reader = fake.NewClientBuilder().WithLists(&nn).Build()
+			lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+				calls++
+				nn := &corev1.NamespaceList{}
+				Expect(reader.List(ctx, nn)).To(Succeed())
+				if opts.IncludeAccess {
+					for i := range nn.Items {
+						nn.Items[i].Annotations = map[string]string{namespacelister.AnnotationAccess: `{"pods":["get"]}`}
+					}
+				}
+				return nn, nil
+			})
+			handler = namespacelister.NewListNamespacesHandler(log, reader, lister, nil)
+		})
+
+		It("returns the pages of a single snapshot", func() {
+			// when
+			code, first := get("myuser", "limit=2")
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(names(first)).To(Equal([]string{"ns-1", "ns-2"}))
+			Expect(first.Continue).NotTo(BeEmpty())
+			Expect(first.RemainingItemCount).To(HaveValue(BeEquivalentTo(3)))
+
+			// when
+			code, second := get("myuser", "limit=2&continue="+first.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(names(second)).To(Equal([]string{"ns-3", "ns-4"}))
+			Expect(second.RemainingItemCount).To(HaveValue(BeEquivalentTo(1)))
+
+			// when
+			code, last := get("myuser", "limit=2&continue="+second.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(names(last)).To(Equal([]string{"ns-5"}))
+			Expect(last.Continue).To(BeEmpty())
+			Expect(last.RemainingItemCount).To(BeNil())
+			Expect(calls).To(Equal(1))
+		})
+
+		It("reads the following pages from the cache", func() {
+			// given
+			_, first := get("myuser", "limit=2")
+			Expect(reader.Delete(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-3"}})).To(Succeed())
+
+			// when
+			code, second := get("myuser", "limit=2&continue="+first.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(names(second)).To(Equal([]string{"ns-4"}))
+			Expect(second.Continue).NotTo(BeEmpty())
+		})
+
+		It("keeps the access summary of the following pages", func() {
+			// given
+			_, first := get("myuser", "limit=2&includeAccess=true")
+
+			// when
+			code, second := get("myuser", "limit=2&includeAccess=true&continue="+first.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(second.Items).To(HaveLen(2))
+			for _, ns := range second.Items {
+				Expect(ns.Annotations).To(HaveKeyWithValue(namespacelister.AnnotationAccess, `{"pods":["get"]}`))
+			}
+		})
+
+		It("evicts the oldest lists of a user paginating too many lists", func() {
+			// given
+			_, other := get("otheruser", "limit=2")
+			_, oldest := get("myuser", "limit=2")
+			for range 8 {
+				_, _ = get("myuser", "limit=2")
+			}
+
+			// when
+			code, _ := get("myuser", "limit=2&continue="+oldest.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusGone))
+
+			// when
+			code, _ = get("otheruser", "limit=2&continue="+other.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+		})
+
+		It("returns the whole list when it fits in the limit", func() {
+			// when
+			code, nn := get("myuser", "limit=5")
+
+			// then
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(nn.Items).To(HaveLen(5))
+			Expect(nn.Continue).To(BeEmpty())
+		})
+
+		It("rejects the continue token of another user", func() {
+			// given
+			_, first := get("myuser", "limit=2")
+
+			// when
+			code, _ := get("otheruser", "limit=2&continue="+first.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects the continue token with different list options", func() {
+			// given
+			_, first := get("myuser", "limit=2")
+
+			// when
+			code, _ := get("myuser", "limit=2&verb=delete&continue="+first.Continue)
+
+			// then
+			Expect(code).To(Equal(http.StatusBadRequest))
+		})
+
+		DescribeTable("rejects invalid pagination parameters", func(query string, expectedCode int) {
+			// when
+			code, _ := get("myuser", query)
+
+			// then
+			Expect(code).To(Equal(expectedCode))
+			Expect(calls).To(BeZero())
+		},
+			Entry("invalid limit", "limit=many", http.StatusBadRequest),
+			Entry("negative limit", "limit=-1", http.StatusBadRequest),
+			Entry("malformed continue token", "continue=%25%25", http.StatusBadRequest),
+			Entry("unknown continue token", "continue=eyJzbmFwc2hvdCI6ImFiYyIsInN0YXJ0IjoyfQ", http.StatusGone),
+		)
+	})
+
+	Context("when a Table is accepted", func() {
+		var handler http.Handler
+
+		BeforeEach(func() {
+			lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+				return &corev1.NamespaceList{Items: []corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "myns", CreationTimestamp: metav1.NewTime(time.Now().Add(-3 * time.Hour))},
+						Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+					},
+				}}, nil
+			})
+			handler = namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+		})
+
+		DescribeTable("returns the namespaces as a Table", func(query string, expectedKind string) {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+			r.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io,application/json;as=Table;v=v1beta1;g=meta.k8s.io,application/json")
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+			// when
+			handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeTable))
+			t := metav1.Table{}
+			Expect(json.NewDecoder(w.Result().Body).Decode(&t)).To(Succeed())
+			Expect(t.Kind).To(Equal("Table"))
+			Expect(t.APIVersion).To(Equal("meta.k8s.io/v1"))
+			Expect(t.ColumnDefinitions).To(HaveLen(3))
+			Expect([]string{t.ColumnDefinitions[0].Name, t.ColumnDefinitions[1].Name, t.ColumnDefinitions[2].Name}).To(Equal([]string{"Name", "Status", "Age"}))
+			Expect(t.Rows).To(HaveLen(1))
+			Expect(t.Rows[0].Cells).To(Equal([]any{"myns", "Active", "3h"}))
+			if expectedKind == "" {
+				Expect(t.Rows[0].Object.Raw).To(BeEmpty())
+				return
+			}
+			obj := metav1.PartialObjectMetadata{}
+			Expect(json.Unmarshal(t.Rows[0].Object.Raw, &obj)).To(Succeed())
+			Expect(obj.Kind).To(Equal(expectedKind))
+			Expect(obj.Name).To(Equal("myns"))
+		},
+			Entry("with the metadata by default", "", "PartialObjectMetadata"),
+			Entry("with the metadata", "includeObject=Metadata", "PartialObjectMetadata"),
+			Entry("with the namespace", "includeObject=Object", "Namespace"),
+			Entry("without the object", "includeObject=None", ""),
+		)
+
+		DescribeTable("returns the NamespaceList when preferred", func(accept string) {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", accept)
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+			// when
+			handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		},
+			Entry("no Accept header", ""),
+			Entry("JSON", "application/json"),
+			Entry("JSON before Table", "application/json, application/json;as=Table;v=v1;g=meta.k8s.io"),
+			Entry("any media type", "*/*"),
+		)
+
+		It("rejects an invalid includeObject", func() {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/?includeObject=All", nil)
+			r.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io")
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+			// when
+			handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when negotiating the media type", func() {
+		var handler http.Handler
+
+		BeforeEach(func() {
+			lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+				return &corev1.NamespaceList{Items: []corev1.Namespace{
+					{ObjectMeta: metav1.ObjectMeta{Name: "myns", Labels: map[string]string{"key": "value"}}},
+				}}, nil
+			})
+			handler = namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+		})
+
+		list := func(accept string) *http.Response {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", accept)
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+			handler.ServeHTTP(w, r)
+			return w.Result()
+		}
+
+		DescribeTable("returns the NamespaceList", func(accept, expectedContentType string) {
+			// when
+			rs := list(accept)
+
+			// then
+			Expect(rs.StatusCode).To(Equal(http.StatusOK))
+			Expect(rs.Header.Get(namespacelister.HttpContentType)).To(Equal(expectedContentType))
+			b, err := io.ReadAll(rs.Body)
+			Expect(err).NotTo(HaveOccurred())
+			info, ok := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), expectedContentType)
+			Expect(ok).To(BeTrue())
+			nn := corev1.NamespaceList{}
+			_, gvk, err := info.Serializer.Decode(b, nil, &nn)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk.Kind).To(Equal("NamespaceList"))
+			Expect(nn.Items).To(HaveLen(1))
+			Expect(nn.Items[0].Name).To(Equal("myns"))
+			Expect(nn.Items[0].Labels).To(Equal(map[string]string{"key": "value"}))
+		},
+			Entry("in YAML", "application/yaml", "application/yaml"),
+			Entry("in Protobuf", "application/vnd.kubernetes.protobuf", "application/vnd.kubernetes.protobuf"),
+			Entry("in Protobuf when preferred", "application/vnd.kubernetes.protobuf, application/json", "application/vnd.kubernetes.protobuf"),
+		)
+
+		DescribeTable("returns a PartialObjectMetadataList", func(accept string) {
+			// when
+			rs := list(accept)
+
+			// then
+			Expect(rs.StatusCode).To(Equal(http.StatusOK))
+			b, err := io.ReadAll(rs.Body)
+			Expect(err).NotTo(HaveOccurred())
+			mt, _, err := mime.ParseMediaType(rs.Header.Get(namespacelister.HttpContentType))
+			Expect(err).NotTo(HaveOccurred())
+			info, ok := runtime.SerializerInfoForMediaType(metaCodecs.SupportedMediaTypes(), mt)
+			Expect(ok).To(BeTrue())
+			pl := metav1.PartialObjectMetadataList{}
+			_, gvk, err := info.Serializer.Decode(b, nil, &pl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk.Kind).To(Equal("PartialObjectMetadataList"))
+			Expect(pl.Items).To(HaveLen(1))
+			Expect(pl.Items[0].Name).To(Equal("myns"))
+			Expect(pl.Items[0].Labels).To(Equal(map[string]string{"key": "value"}))
+		},
+			Entry("in JSON", "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1"),
+			Entry("in Protobuf", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json"),
+		)
+
+		DescribeTable("returns 406 Not Acceptable for unsupported media types", func(accept string) {
+			// when
+			rs := list(accept)
+
+			// then
+			Expect(rs.StatusCode).To(Equal(http.StatusNotAcceptable))
+			s := metav1.Status{}
+			Expect(json.NewDecoder(rs.Body).Decode(&s)).To(Succeed())
+			Expect(s.Kind).To(Equal("Status"))
+			Expect(s.Reason).To(Equal(metav1.StatusReasonNotAcceptable))
+		},
+			Entry("unsupported media type", "text/html"),
+			Entry("unsupported Table version", "application/json;as=Table;v=v1beta1;g=meta.k8s.io"),
+			Entry("Table in Protobuf", "application/vnd.kubernetes.protobuf;as=Table;v=v1;g=meta.k8s.io"),
+			Entry("unsupported conversion", "application/json;as=PartialObjectMetadata;v=v1;g=meta.k8s.io"),
+		)
+	})
+
+	It("returns 401 Unauthorized when the request is not authenticated", func() {
+		// given
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			return &corev1.NamespaceList{}, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+		s := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&s)).To(Succeed())
+		Expect(s.Reason).To(Equal(metav1.StatusReasonUnauthorized))
+	})
 })
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const queryParamFormat string = "format"
+
+var _ http.Handler = &AccessMatrixHandler{}
+
+// AccessMatrixHandler streams the namespaces each subject is granted `get` access on.
+// The output format is selected with the `format` query parameter.
+type AccessMatrixHandler struct {
+	log   *slog.Logger
+	index *AccessIndex
+}
+
+func NewAccessMatrixHandler(log *slog.Logger, index *AccessIndex) http.Handler {
+	return &AccessMatrixHandler{
+		log:   log,
+		index: index,
+	}
+}
+
+func (h *AccessMatrixHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := valueOrDefault(r.URL.Query().Get(queryParamFormat), MatrixFormatJSONLines)
+	mw, contentType, err := NewMatrixWriter(w, format)
+	if err != nil {
+		writeStatus(h.log, w, kerrors.NewBadRequest(err.Error()))
+		return
+	}
+	h.log.Info("received access matrix request", "format", format)
+
+	// the status can not be changed once the first row is written
+	started := false
+	err = h.index.VisitMatrix(r.Context(), func(row MatrixRow) error {
+		if !started {
+			started = true
+			w.Header().Set(HttpContentType, contentType)
+		}
+		return mw.Write(row)
+	})
+	if err == nil {
+		err = mw.Flush()
+	}
+	switch {
+	case err != nil && !started:
+		writeStatus(h.log, w, err)
+	case err != nil:
+		h.log.Error("error streaming the access matrix", "error", err)
+	case !started:
+		// no subjects are granted access
+		w.Header().Set(HttpContentType, contentType)
+		w.WriteHeader(http.StatusOK)
+	}
+}
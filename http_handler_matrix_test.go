@@ -0,0 +1,83 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("HttpHandlerMatrix", func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}}},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		handler = namespacelister.NewAccessMatrixHandler(log, namespacelister.NewAccessIndex(context.TODO(), reader, log))
+	})
+
+	DescribeTable("streams the access matrix", func(query, expectedContentType, expectedBody string) {
+		// given
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/access-matrix"+query, nil)
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(expectedContentType))
+		Expect(w.Body.String()).To(Equal(expectedBody))
+	},
+		Entry("as JSON Lines by default", "", namespacelister.HttpContentTypeJSONLines,
+			`{"kind":"User","name":"user","clusterWide":false,"namespaces":["myns-1","myns-2"]}`+"\n"),
+		Entry("as CSV", "?format=csv", namespacelister.HttpContentTypeCSV,
+			"kind,subjectNamespace,name,clusterWide,namespace\nUser,,user,false,myns-1\nUser,,user,false,myns-2\n"),
+	)
+
+	It("rejects unsupported formats", func() {
+		// given
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/access-matrix?format=xml", nil)
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
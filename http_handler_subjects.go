@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+var _ http.Handler = &SubjectsHandler{}
+
+// SubjectsHandler lists the users, groups, and ServiceAccounts allowed to get a namespace
+type SubjectsHandler struct {
+	log       *slog.Logger
+	explainer *Explainer
+}
+
+func NewSubjectsHandler(log *slog.Logger, explainer *Explainer) http.Handler {
+	return &SubjectsHandler{
+		log:       log,
+		explainer: explainer,
+	}
+}
+
+func (h *SubjectsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	h.log.Info("received subjects request", "namespace", name)
+
+	nss, err := h.explainer.SubjectsFor(r.Context(), name)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	b, err := json.Marshal(nss)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	w.Header().Add(HttpContentType, HttpContentTypeApplication)
+	if _, err := w.Write(b); err != nil {
+		h.log.Error("error writing reply", "error", err)
+	}
+}
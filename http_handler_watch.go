@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+const (
+	queryParamWatch           string = "watch"
+	queryParamResourceVersion string = "resourceVersion"
+	queryParamTimeoutSeconds  string = "timeoutSeconds"
+)
+
+// WatchOptions are the options of a watch request
+type WatchOptions struct {
+	Watch bool
+	// ResourceVersion is the version of the list the watch starts from.
+	// If empty or `0`, the watch starts with an ADDED event for every namespace.
+	ResourceVersion string
+	// Timeout, if set, ends the watch after the given duration
+	Timeout time.Duration
+}
+
+// parseWatchOptions reads the WatchOptions from the request query parameters
+func parseWatchOptions(q url.Values) (WatchOptions, error) {
+	opts := WatchOptions{ResourceVersion: q.Get(queryParamResourceVersion)}
+	if v := q.Get(queryParamWatch); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, kerrors.NewBadRequest(fmt.Sprintf("%s %q is not a boolean", queryParamWatch, v))
+		}
+		opts.Watch = b
+	}
+	if v := q.Get(queryParamTimeoutSeconds); v != "" {
+		s, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || s < 0 {
+			return opts, kerrors.NewBadRequest(fmt.Sprintf("%s %q is not a non-negative integer", queryParamTimeoutSeconds, v))
+		}
+		opts.Timeout = time.Duration(s) * time.Second
+	}
+	return opts, nil
+}
+
+// watch streams the changes of the namespaces the user has access to.
+// On every change of the cached resources that can affect the user the namespaces are listed again,
+// and the differences with the previous list are sent as WatchEvents.
+// Events carry the namespaces in the negotiated representation, with the version of the list they come from:
+// clients can resume watching from it.
+func (h *ListNamespacesHandler) watch(w http.ResponseWriter, r *http.Request, ui user.Info, opts ListOptions, wopts WatchOptions, rep *representation) {
+	if h.changes == nil {
+		writeStatus(h.log, w, kerrors.NewBadRequest("watch is not supported"))
+		return
+	}
+
+	ctx := r.Context()
+	if wopts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wopts.Timeout)
+		defer cancel()
+	}
+
+	// subscribe before listing: changes happening while listing trigger a new list
+	changed, unsubscribe := h.changes.Subscribe(affectsUser(ui))
+	defer unsubscribe()
+
+	nn, err := h.lister.ListNamespaces(ctx, ui, opts)
+	if err != nil {
+		writeStatus(h.log, w, err)
+		return
+	}
+
+	// the watch can start from the requested version as long as the user's list did not change
+	rv := h.changes.ResourceVersion(nn.Items)
+	fromList := wopts.ResourceVersion == "" || wopts.ResourceVersion == "0"
+	if !fromList && wopts.ResourceVersion != rv {
+		writeStatus(h.log, w, kerrors.NewResourceExpired(fmt.Sprintf("too old resource version: %s (%s)", wopts.ResourceVersion, rv)))
+		return
+	}
+
+	h.log.Info("watching namespaces", userLogAttr(ui), "resourceVersion", wopts.ResourceVersion)
+	w.Header().Set(HttpContentType, rep.streamContentType())
+	w.WriteHeader(http.StatusOK)
+	ew := &eventWriter{
+		rep: rep,
+		enc: streaming.NewEncoder(rep.info.StreamSerializer.Framer.NewFrameWriter(w), rep.info.StreamSerializer.Serializer),
+		rc:  http.NewResponseController(w),
+	}
+	if err := ew.rc.Flush(); err != nil {
+		h.log.Error("error flushing watch response", "error", err)
+		return
+	}
+
+	var initial []corev1.Namespace
+	if !fromList {
+		initial = nn.Items
+	}
+	if !h.writeEvents(ew, rv, namespaceEvents(initial, nn.Items)) {
+		return
+	}
+
+	current := nn.Items
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+
+		nn, err := h.lister.ListNamespaces(ctx, ui, opts)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.writeErrorEvent(ew, err)
+			}
+			return
+		}
+		if !h.writeEvents(ew, h.changes.ResourceVersion(nn.Items), namespaceEvents(current, nn.Items)) {
+			return
+		}
+		current = nn.Items
+	}
+}
+
+// eventWriter writes the WatchEvents in the negotiated representation,
+// framed as kube-apiserver does for the media type
+type eventWriter struct {
+	rep *representation
+	enc streaming.Encoder
+	rc  *http.ResponseController
+}
+
+// write writes the event carrying obj
+func (ew *eventWriter) write(t watch.EventType, obj runtime.Object) error {
+	b := bytes.Buffer{}
+	if err := ew.rep.encode(&b, obj); err != nil {
+		return err
+	}
+	return ew.enc.Encode(&metav1.WatchEvent{Type: string(t), Object: runtime.RawExtension{Raw: b.Bytes()}})
+}
+
+// writeEvents writes the events and flushes them to the client.
+// The namespaces carry the resource version rv of the list they come from,
+// instead of their own: the version a client resumes watching from must be one of this server.
+func (h *ListNamespacesHandler) writeEvents(ew *eventWriter, rv string, ee []watch.Event) bool {
+	if len(ee) == 0 {
+		return true
+	}
+
+	for _, e := range ee {
+		ns := e.Object.(*corev1.Namespace)
+		ns.ResourceVersion = rv
+		obj, err := ew.rep.object(ns)
+		if err != nil {
+			h.log.Error("error building watch event", "error", err)
+			return false
+		}
+		if err := ew.write(e.Type, obj); err != nil {
+			h.log.Error("error writing watch event", "error", err)
+			return false
+		}
+	}
+	if err := ew.rc.Flush(); err != nil {
+		h.log.Error("error flushing watch events", "error", err)
+		return false
+	}
+	return true
+}
+
+// writeErrorEvent reports err to the client as an ERROR event carrying its Status
+func (h *ListNamespacesHandler) writeErrorEvent(ew *eventWriter, err error) {
+	s := kerrors.NewInternalError(err).Status()
+	if serr := kerrors.APIStatus(nil); errors.As(err, &serr) {
+		s = serr.Status()
+	}
+	s.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+
+	if err := ew.write(watch.Error, &s); err != nil {
+		h.log.Error("error writing watch event", "error", err)
+		return
+	}
+	if err := ew.rc.Flush(); err != nil {
+		h.log.Error("error flushing watch events", "error", err)
+	}
+}
+
+// affectsUser returns the filter of the changes that can affect the namespaces the user has access to.
+// Bindings change frequently, the changes of the ones the user is not a subject of are ignored.
+// The changes of the other resources are always relevant.
+func affectsUser(ui user.Info) ChangeFilter {
+	subjects := sets.New(userIndexSubjects(ui)...)
+	return func(objs []any) bool {
+		for _, o := range objs {
+			if d, ok := o.(toolscache.DeletedFinalStateUnknown); ok {
+				o = d.Obj
+			}
+
+			var (
+				ss []rbacv1.Subject
+				ns string
+			)
+			switch b := o.(type) {
+			case *rbacv1.RoleBinding:
+				ss, ns = b.Subjects, b.Namespace
+			case *rbacv1.ClusterRoleBinding:
+				ss = b.Subjects
+			default:
+				return true
+			}
+			for _, s := range ss {
+				if is, ok := newIndexSubject(s, ns); ok && subjects.Has(is) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// namespaceEvents returns the events turning the before list of namespaces into the after one:
+// ADDED and MODIFIED events in the after list's order, then DELETED events sorted by name.
+// Event objects are copies of the namespaces.
+func namespaceEvents(before, after []corev1.Namespace) []watch.Event {
+	previous := make(map[string]*corev1.Namespace, len(before))
+	for i := range before {
+		previous[before[i].Name] = &before[i]
+	}
+
+	ee := []watch.Event{}
+	for i := range after {
+		ns := &after[i]
+		p, ok := previous[ns.Name]
+		delete(previous, ns.Name)
+		switch {
+		case !ok:
+			ee = append(ee, watch.Event{Type: watch.Added, Object: ns.DeepCopy()})
+		case !equality.Semantic.DeepEqual(p, ns):
+			ee = append(ee, watch.Event{Type: watch.Modified, Object: ns.DeepCopy()})
+		}
+	}
+
+	deleted := make([]string, 0, len(previous))
+	for n := range previous {
+		deleted = append(deleted, n)
+	}
+	slices.Sort(deleted)
+	for _, n := range deleted {
+		ee = append(ee, watch.Event{Type: watch.Deleted, Object: previous[n].DeepCopy()})
+	}
+	return ee
+}
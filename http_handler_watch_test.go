@@ -0,0 +1,322 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	restclientwatch "k8s.io/client-go/rest/watch"
+)
+
+var _ = Describe("HttpHandlerWatch", func() {
+	var (
+		log     *slog.Logger
+		changes *namespacelister.ChangeNotifier
+		srv     *httptest.Server
+
+		mu         sync.Mutex
+		namespaces []corev1.Namespace
+		lists      int
+	)
+
+	namespace := func(name string, labels map[string]string) corev1.Namespace {
+		return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	// setNamespaces updates the namespaces returned by the lister and notifies the change
+	setNamespaces := func(nn ...corev1.Namespace) {
+		mu.Lock()
+		namespaces = nn
+		mu.Unlock()
+		changes.Notify()
+	}
+
+	// nextEvent reads the next event from the stream
+	nextEvent := func(dec *json.Decoder) (watch.EventType, corev1.Namespace) {
+		e := metav1.WatchEvent{}
+		ExpectWithOffset(1, dec.Decode(&e)).To(Succeed())
+		ns := corev1.Namespace{}
+		ExpectWithOffset(1, json.Unmarshal(e.Object.Raw, &ns)).To(Succeed())
+		ExpectWithOffset(1, ns.Kind).To(Equal("Namespace"))
+		return watch.EventType(e.Type), ns
+	}
+
+	BeforeEach(func() {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+		changes = namespacelister.NewChangeNotifier()
+		namespaces = []corev1.Namespace{namespace("myns", nil), namespace("otherns", nil)}
+		lists = 0
+
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			lists++
+			return &corev1.NamespaceList{Items: namespaces}, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, changes)
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+			handler.ServeHTTP(w, r)
+		}))
+		DeferCleanup(srv.Close)
+	})
+
+	It("streams the namespaces and their changes", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(rsp.Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		dec := json.NewDecoder(rsp.Body)
+		t, ns := nextEvent(dec)
+		Expect(t).To(Equal(watch.Added))
+		Expect(ns.Name).To(Equal("myns"))
+		t, ns = nextEvent(dec)
+		Expect(t).To(Equal(watch.Added))
+		Expect(ns.Name).To(Equal("otherns"))
+
+		// when
+		setNamespaces(namespace("myns", map[string]string{"key": "value"}), namespace("newns", nil))
+
+		// then
+		t, ns = nextEvent(dec)
+		Expect(t).To(Equal(watch.Modified))
+		Expect(ns.Name).To(Equal("myns"))
+		Expect(ns.Labels).To(Equal(map[string]string{"key": "value"}))
+		t, ns = nextEvent(dec)
+		Expect(t).To(Equal(watch.Added))
+		Expect(ns.Name).To(Equal("newns"))
+		t, ns = nextEvent(dec)
+		Expect(t).To(Equal(watch.Deleted))
+		Expect(ns.Name).To(Equal("otherns"))
+	}, SpecTimeout(10*time.Second))
+
+	It("streams only the changes following the list's resourceVersion", func(ctx context.Context) {
+		// given
+		rsp, err := srv.Client().Get(srv.URL + "/")
+		Expect(err).NotTo(HaveOccurred())
+		nn := corev1.NamespaceList{}
+		Expect(json.NewDecoder(rsp.Body).Decode(&nn)).To(Succeed())
+		Expect(rsp.Body.Close()).To(Succeed())
+		Expect(nn.ResourceVersion).To(Equal(changes.ResourceVersion(nn.Items)))
+		// changes not modifying the user's list keep its resourceVersion valid
+		changes.Notify()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true&resourceVersion="+nn.ResourceVersion, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		rsp, err = srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		setNamespaces(namespace("myns", nil))
+
+		// then
+		t, ns := nextEvent(json.NewDecoder(rsp.Body))
+		Expect(t).To(Equal(watch.Deleted))
+		Expect(ns.Name).To(Equal("otherns"))
+	}, SpecTimeout(10*time.Second))
+
+	It("sets the list's resourceVersion on the events", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+		dec := json.NewDecoder(rsp.Body)
+		_, ns := nextEvent(dec)
+		Expect(ns.ResourceVersion).To(Equal(changes.ResourceVersion(namespaces)))
+		_, _ = nextEvent(dec)
+
+		// when
+		setNamespaces(namespace("myns", nil))
+
+		// then
+		_, ns = nextEvent(dec)
+		Expect(ns.Name).To(Equal("otherns"))
+		Expect(ns.ResourceVersion).To(Equal(changes.ResourceVersion([]corev1.Namespace{namespace("myns", nil)})))
+	}, SpecTimeout(10*time.Second))
+
+	It("ignores the changes of bindings the user is not a subject of", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+		dec := json.NewDecoder(rsp.Body)
+		_, _ = nextEvent(dec)
+		_, _ = nextEvent(dec)
+		listCount := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return lists
+		}
+		Expect(listCount()).To(Equal(1))
+
+		// when
+		changes.Notify(&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "myns"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "other"}},
+		})
+
+		// then
+		Consistently(listCount).WithTimeout(200 * time.Millisecond).Should(Equal(1))
+
+		// when
+		changes.Notify(&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "myuser"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "myuser"}},
+		})
+
+		// then
+		Eventually(listCount).Should(Equal(2))
+	}, SpecTimeout(10*time.Second))
+
+	It("ends the watch after timeoutSeconds", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true&timeoutSeconds=1", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		b, err := io.ReadAll(rsp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).NotTo(BeEmpty())
+	}, SpecTimeout(10*time.Second))
+
+	It("streams Tables when accepted", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io")
+
+		// when
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		e := metav1.WatchEvent{}
+		Expect(json.NewDecoder(rsp.Body).Decode(&e)).To(Succeed())
+		Expect(e.Type).To(BeEquivalentTo(watch.Added))
+		t := metav1.Table{}
+		Expect(json.Unmarshal(e.Object.Raw, &t)).To(Succeed())
+		Expect(t.Kind).To(Equal("Table"))
+		Expect(t.Rows).To(HaveLen(1))
+		Expect(t.Rows[0].Cells[0]).To(Equal("myns"))
+	}, SpecTimeout(10*time.Second))
+
+	It("streams PartialObjectMetadata in Protobuf when accepted", func(ctx context.Context) {
+		// given
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Accept", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json")
+
+		// when
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(rsp.Header.Get(namespacelister.HttpContentType)).To(Equal("application/vnd.kubernetes.protobuf;stream=watch"))
+		info, ok := runtime.SerializerInfoForMediaType(metaCodecs.SupportedMediaTypes(), runtime.ContentTypeProtobuf)
+		Expect(ok).To(BeTrue())
+		dec := restclientwatch.NewDecoder(
+			streaming.NewDecoder(info.StreamSerializer.Framer.NewFrameReader(rsp.Body), info.StreamSerializer.Serializer),
+			metaCodecs.UniversalDeserializer())
+		defer dec.Close()
+		t, obj, err := dec.Decode()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(Equal(watch.Added))
+		Expect(obj).To(BeAssignableToTypeOf(&metav1.PartialObjectMetadata{}))
+		Expect(obj.(*metav1.PartialObjectMetadata).Name).To(Equal("myns"))
+	}, SpecTimeout(10*time.Second))
+
+	It("rejects watch requests in YAML", func() {
+		// given
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/?watch=true", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Accept", "application/yaml")
+
+		// when
+		rsp, err := srv.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotAcceptable))
+	})
+
+	It("returns 410 Gone when the user's list changed since the resourceVersion", func() {
+		// given
+		rsp, err := srv.Client().Get(srv.URL + "/")
+		Expect(err).NotTo(HaveOccurred())
+		nn := corev1.NamespaceList{}
+		Expect(json.NewDecoder(rsp.Body).Decode(&nn)).To(Succeed())
+		Expect(rsp.Body.Close()).To(Succeed())
+		setNamespaces(namespace("myns", nil))
+
+		// when
+		rsp, err = srv.Client().Get(srv.URL + "/?watch=true&resourceVersion=" + nn.ResourceVersion)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+
+		// then
+		Expect(rsp.StatusCode).To(Equal(http.StatusGone))
+		s := metav1.Status{}
+		Expect(json.NewDecoder(rsp.Body).Decode(&s)).To(Succeed())
+		Expect(s.Reason).To(Equal(metav1.StatusReasonExpired))
+	})
+
+	It("rejects watch requests when changes are not notified", func() {
+		// given
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			Fail("lister should not be invoked")
+			return nil, nil
+		})
+		handler := namespacelister.NewListNamespacesHandler(log, nil, lister, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/?watch=true", nil)
+		r = r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "myuser"}))
+
+		// when
+		handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
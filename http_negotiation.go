@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+)
+
+const (
+	KindTable                     string = "Table"
+	KindPartialObjectMetadata     string = "PartialObjectMetadata"
+	KindPartialObjectMetadataList string = "PartialObjectMetadataList"
+)
+
+var (
+	// codecs serialize the namespaces, and their meta.k8s.io representations, as kube-apiserver does
+	codecs = newCodecs()
+
+	// outputVersions are the versions replies are encoded in
+	outputVersions = schema.GroupVersions{corev1.SchemeGroupVersion, metav1.SchemeGroupVersion}
+)
+
+func newCodecs() serializer.CodecFactory {
+	s := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(s))
+	utilruntime.Must(metav1.AddMetaToScheme(s))
+	return serializer.NewCodecFactory(s)
+}
+
+// outputRestrictions allows converting the namespaces to the given meta.k8s.io/v1 kinds
+type outputRestrictions struct {
+	kinds sets.Set[string]
+}
+
+func (r outputRestrictions) AllowsMediaTypeTransform(_, mimeSubType string, gvk *schema.GroupVersionKind) bool {
+	if gvk == nil {
+		return true
+	}
+	// as in kube-apiserver, Tables are not supported in protobuf
+	if gvk.Kind == KindTable && mimeSubType == "vnd.kubernetes.protobuf" {
+		return false
+	}
+	return gvk.GroupVersion() == metav1.SchemeGroupVersion && r.kinds.Has(gvk.Kind)
+}
+
+func (outputRestrictions) AllowsServerVersion(string) bool { return false }
+
+func (outputRestrictions) AllowsStreamSchema(s string) bool { return s == "watch" }
+
+// representation is the negotiated form of a reply
+type representation struct {
+	info runtime.SerializerInfo
+	// kind is the meta.k8s.io/v1 kind the namespaces are converted to,
+	// empty to reply with the namespaces
+	kind string
+	// table are the options of the Table, if kind is Table
+	table *TableOptions
+}
+
+// negotiateOutput returns the representation accepted by the request among the namespaces
+// and their conversion to the given meta.k8s.io/v1 kinds.
+// If stream is set, only media types supporting watch are accepted.
+// A request accepting none of them is reported as a NotAcceptable error.
+func negotiateOutput(r *http.Request, stream bool, kinds ...string) (*representation, error) {
+	infos := []runtime.SerializerInfo{}
+	for _, i := range codecs.SupportedMediaTypes() {
+		if !stream || i.StreamSerializer != nil {
+			infos = append(infos, i)
+		}
+	}
+
+	mto, ok := negotiation.NegotiateMediaTypeOptions(r.Header.Get("Accept"), infos, outputRestrictions{kinds: sets.New(kinds...)})
+	if !ok {
+		accepted := make([]string, 0, len(infos))
+		for _, i := range infos {
+			accepted = append(accepted, i.MediaType)
+		}
+		return nil, negotiation.NewNotAcceptableError(accepted)
+	}
+
+	rep := &representation{info: mto.Accepted}
+	if mto.Convert != nil {
+		rep.kind = mto.Convert.Kind
+	}
+	if rep.kind == KindTable {
+		topts, err := parseTableOptions(r.URL.Query())
+		if err != nil {
+			return nil, err
+		}
+		rep.table = topts
+	}
+	return rep, nil
+}
+
+// contentType returns the content type of the reply
+func (rep *representation) contentType() string {
+	switch {
+	case rep.kind != "":
+		return fmt.Sprintf("%s;as=%s;v=%s;g=%s", rep.info.MediaType, rep.kind, metav1.SchemeGroupVersion.Version, metav1.GroupName)
+	case rep.info.MediaType == runtime.ContentTypeJSON:
+		return HttpContentTypeApplication
+	default:
+		return rep.info.MediaType
+	}
+}
+
+// streamContentType returns the content type of a watch reply
+func (rep *representation) streamContentType() string {
+	if rep.info.MediaType == runtime.ContentTypeJSON {
+		return rep.contentType()
+	}
+	return rep.info.MediaType + ";stream=watch"
+}
+
+// list returns the object representing the list of namespaces.
+// The list is not modified, as it may be shared with concurrent requests.
+func (rep *representation) list(nn *corev1.NamespaceList) (runtime.Object, error) {
+	switch rep.kind {
+	case KindTable:
+		return namespaceTable(nn, rep.table)
+	case KindPartialObjectMetadataList:
+		pl := &metav1.PartialObjectMetadataList{
+			TypeMeta: metav1.TypeMeta{Kind: KindPartialObjectMetadataList, APIVersion: metav1.SchemeGroupVersion.String()},
+			ListMeta: nn.ListMeta,
+			Items:    make([]metav1.PartialObjectMetadata, 0, len(nn.Items)),
+		}
+		for i := range nn.Items {
+			pl.Items = append(pl.Items, *partialObjectMetadata(&nn.Items[i]))
+		}
+		return pl, nil
+	default:
+		c := *nn
+		return &c, nil
+	}
+}
+
+// object returns the object representing the namespace
+func (rep *representation) object(ns *corev1.Namespace) (runtime.Object, error) {
+	switch rep.kind {
+	case KindTable:
+		return namespaceTable(&corev1.NamespaceList{Items: []corev1.Namespace{*ns}}, rep.table)
+	case KindPartialObjectMetadata:
+		return partialObjectMetadata(ns), nil
+	default:
+		c := *ns
+		c.TypeMeta = metav1.TypeMeta{Kind: "Namespace", APIVersion: corev1.SchemeGroupVersion.Version}
+		return &c, nil
+	}
+}
+
+// encode writes obj in the negotiated media type.
+// JSON is encoded as the objects are, other media types with the apimachinery serializers.
+func (rep *representation) encode(w io.Writer, obj runtime.Object) error {
+	if rep.info.MediaType == runtime.ContentTypeJSON {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return codecs.EncoderForVersion(rep.info.Serializer, outputVersions).Encode(obj, w)
+}
+
+// partialObjectMetadata returns the metadata of the namespace
+func partialObjectMetadata(ns *corev1.Namespace) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{Kind: KindPartialObjectMetadata, APIVersion: metav1.SchemeGroupVersion.String()},
+		ObjectMeta: ns.ObjectMeta,
+	}
+}
@@ -2,20 +2,58 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 const (
 	patternGetNamespaces string = "GET /api/v1/namespaces"
+	patternGetNamespace  string = "GET /api/v1/namespaces/{name}"
+	patternGetMetrics    string = "GET /metrics"
+	patternGetExplain    string = "GET /api/v1/namespaces/{name}/explain"
+	patternGetSubjects   string = "GET /api/v1/namespaces/{name}/subjects"
+	patternGetMatrix     string = "GET /access-matrix"
+	patternNotFound      string = "/"
+
+	// patternNamespaces and patternNamespace match the namespaces endpoints with any method
+	patternNamespaces string = "/api/v1/namespaces"
+	patternNamespace  string = "/api/v1/namespaces/{name}"
 )
 
+// adminAttributes are the attributes a user must be allowed on to access the admin endpoints:
+// as for SubjectAccessReviews, only the users allowed to create them can inspect the access of other users.
+var adminAttributes = authorizer.AttributesRecord{
+	Verb:            "create",
+	APIGroup:        authorizationv1.GroupName,
+	Resource:        "subjectaccessreviews",
+	ResourceRequest: true,
+}
+
 type NamespaceListerServer struct {
 	*http.Server
 
 	logger *slog.Logger
+	mux    *http.ServeMux
+	auth   authenticator.Request
+
+	certFile string
+	keyFile  string
 }
 
 func addLogMiddleware(l *slog.Logger, next http.Handler) http.HandlerFunc {
@@ -25,17 +63,117 @@ func addLogMiddleware(l *slog.Logger, next http.Handler) http.HandlerFunc {
 	}
 }
 
-func NewServer(l *slog.Logger, lister NamespaceLister, userHeader string) *NamespaceListerServer {
+// addAuthenticationMiddleware authenticates the request and stores the user in the request's context.
+// Unauthenticated requests are rejected with 401 Unauthorized.
+// Authentication errors are only logged, except for a missing user header.
+func addAuthenticationMiddleware(l *slog.Logger, auth authenticator.Request, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rs, ok, err := auth.AuthenticateRequest(r)
+		if err != nil || !ok {
+			l.Info("unable to authenticate the request", "request", r.URL.Path, "error", err)
+			msg := http.StatusText(http.StatusUnauthorized)
+			if errors.Is(err, errMissingUserHeader) {
+				msg = err.Error()
+			}
+			writeStatus(l, w, kerrors.NewUnauthorized(msg))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(request.WithUser(r.Context(), rs.User)))
+	}
+}
+
+// notFoundHandler replies to the requests not matching any endpoint with a NotFound Status
+func notFoundHandler(l *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(l, w, kerrors.NewGenericServerResponse(http.StatusNotFound, r.Method, schema.GroupResource{}, "", "", 0, false))
+	}
+}
+
+// methodNotSupportedHandler replies to the requests for the namespaces with a method
+// other than GET with a MethodNotSupported Status, as kube-apiserver does.
+// GET requests reach it only if the endpoint is not served and get a NotFound Status.
+func methodNotSupportedHandler(l *slog.Logger) http.HandlerFunc {
+	notFound := notFoundHandler(l)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			notFound(w, r)
+			return
+		}
+		writeStatus(l, w, kerrors.NewMethodNotSupported(corev1.Resource("namespaces"), r.Method))
+	}
+}
+
+// addAdminMiddleware forbids the request to users not allowed on the adminAttributes.
+// It expects the user to be stored in the request's context by the authentication middleware.
+func addAdminMiddleware(l *slog.Logger, authz authorizer.Authorizer, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ui, ok := request.UserFrom(r.Context())
+		if !ok {
+			writeStatus(l, w, kerrors.NewUnauthorized(http.StatusText(http.StatusUnauthorized)))
+			return
+		}
+
+		attrs := adminAttributes
+		attrs.User = ui
+		d, _, err := authz.Authorize(r.Context(), attrs)
+		if err != nil {
+			writeStatus(l, w, err)
+			return
+		}
+		if d != authorizer.DecisionAllow {
+			l.Info("forbidden access to admin endpoint", "request", r.URL.Path, userLogAttr(ui))
+			gr := schema.GroupResource{Group: attrs.APIGroup, Resource: attrs.Resource}
+			writeStatus(l, w, kerrors.NewForbidden(gr, "", fmt.Errorf("user %q is not an administrator", ui.GetName())))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// NewServer builds the server with the discovery endpoints. If changes is not nil, watch requests are served.
+// The namespaces of the paginated lists are read back from the reader.
+func NewServer(l *slog.Logger, reader client.Reader, lister NamespaceLister, changes *ChangeNotifier, auth authenticator.Request) *NamespaceListerServer {
 	// configure the server
 	h := http.NewServeMux()
-	h.Handle(patternGetNamespaces, addLogMiddleware(l, NewListNamespacesHandler(l, lister, userHeader)))
-	return &NamespaceListerServer{
+	s := &NamespaceListerServer{
 		Server: &http.Server{
 			Addr:              getAddress(),
 			Handler:           h,
 			ReadHeaderTimeout: 3 * time.Second,
 		},
 		logger: l,
+		mux:    h,
+		auth:   auth,
+	}
+	s.Handle(patternGetNamespaces, NewListNamespacesHandler(l, reader, lister, changes))
+	h.Handle(patternGetMetrics, promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	registerDiscoveryHandlers(s, changes != nil)
+	h.Handle(patternNamespaces, addLogMiddleware(l, methodNotSupportedHandler(l)))
+	h.Handle(patternNamespace, addLogMiddleware(l, methodNotSupportedHandler(l)))
+	h.Handle(patternNotFound, addLogMiddleware(l, notFoundHandler(l)))
+	return s
+}
+
+// Handle registers an endpoint reserved to authenticated users
+func (s *NamespaceListerServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, addLogMiddleware(s.logger, addAuthenticationMiddleware(s.logger, s.auth, handler)))
+}
+
+// HandleAdmin registers an endpoint reserved to the users allowed on the adminAttributes
+func (s *NamespaceListerServer) HandleAdmin(pattern string, authz authorizer.Authorizer, handler http.Handler) {
+	s.mux.Handle(pattern, addLogMiddleware(s.logger, addAuthenticationMiddleware(s.logger, s.auth, addAdminMiddleware(s.logger, authz, handler))))
+}
+
+// EnableTLS configures the server to serve TLS with the given certificate and key.
+// Client certificates are requested but not required during the handshake,
+// they are verified by the authenticator.
+func (s *NamespaceListerServer) EnableTLS(certFile, keyFile string) {
+	s.certFile, s.keyFile = certFile, keyFile
+	s.TLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequestClientCert,
 	}
 }
 
@@ -55,5 +193,8 @@ func (s *NamespaceListerServer) Start(ctx context.Context) error {
 
 	// start server
 	s.logger.Info("serving...")
+	if s.TLSConfig != nil {
+		return s.ListenAndServeTLS(s.certFile, s.keyFile)
+	}
 	return s.ListenAndServe()
 }
@@ -0,0 +1,243 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type AuthenticatorMock func(r *http.Request) (*authenticator.Response, bool, error)
+
+func (m AuthenticatorMock) AuthenticateRequest(r *http.Request) (*authenticator.Response, bool, error) {
+	return m(r)
+}
+
+var _ = Describe("NamespaceListerServer", func() {
+	var (
+		log    *slog.Logger
+		listed bool
+		lister NamespaceListerMock
+	)
+
+	BeforeEach(func() {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+		listed = false
+		lister = NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			listed = true
+			return &corev1.NamespaceList{}, nil
+		})
+	})
+
+	It("serves the namespaces to authenticated users", func() {
+		// given
+		auth := AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "myuser"}}, true, nil
+		})
+		s := namespacelister.NewServer(log, nil, lister, nil, auth)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil)
+
+		// when
+		s.Handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(listed).To(BeTrue())
+	})
+
+	It("rejects unauthenticated requests before listing namespaces", func() {
+		// given
+		auth := AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return nil, false, nil
+		})
+		s := namespacelister.NewServer(log, nil, lister, nil, auth)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil)
+		r.Header.Add("Impersonate-User", "myuser")
+
+		// when
+		s.Handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(listed).To(BeFalse())
+		st := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&st)).To(Succeed())
+		Expect(st.Kind).To(Equal("Status"))
+		Expect(st.Code).To(BeEquivalentTo(http.StatusUnauthorized))
+		Expect(st.Reason).To(Equal(metav1.StatusReasonUnauthorized))
+	})
+
+	It("reports the missing user header", func() {
+		// given
+		auth := namespacelister.NewHeaderAuthenticator(namespacelister.UserHeaders{Username: "X-Email"})
+		s := namespacelister.NewServer(log, nil, lister, nil, auth)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil)
+
+		// when
+		s.Handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(listed).To(BeFalse())
+		st := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&st)).To(Succeed())
+		Expect(st.Reason).To(Equal(metav1.StatusReasonUnauthorized))
+		Expect(st.Message).To(Equal("missing user header X-Email"))
+	})
+
+	It("returns a NotFound Status for unknown paths", func() {
+		// given
+		auth := AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "myuser"}}, true, nil
+		})
+		s := namespacelister.NewServer(log, nil, lister, nil, auth)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/apis/apps/v1/deployments", nil)
+
+		// when
+		s.Handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusNotFound))
+		Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		st := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&st)).To(Succeed())
+		Expect(st.Kind).To(Equal("Status"))
+		Expect(st.Code).To(BeEquivalentTo(http.StatusNotFound))
+		Expect(st.Reason).To(Equal(metav1.StatusReasonNotFound))
+		Expect(listed).To(BeFalse())
+	})
+
+	DescribeTable("returns a MethodNotSupported Status for the unsupported methods on namespaces", func(method, path string) {
+		// given
+		auth := AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "myuser"}}, true, nil
+		})
+		s := namespacelister.NewServer(log, nil, lister, nil, auth)
+		got := false
+		s.Handle("GET /api/v1/namespaces/{name}", http.HandlerFunc(func(http.ResponseWriter, *http.Request) { got = true }))
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+
+		// when
+		s.Handler.ServeHTTP(w, r)
+
+		// then
+		Expect(w.Result().StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		st := metav1.Status{}
+		Expect(json.NewDecoder(w.Result().Body).Decode(&st)).To(Succeed())
+		Expect(st.Kind).To(Equal("Status"))
+		Expect(st.Reason).To(Equal(metav1.StatusReasonMethodNotAllowed))
+		Expect(listed).To(BeFalse())
+		Expect(got).To(BeFalse())
+	},
+		Entry("create", http.MethodPost, "/api/v1/namespaces"),
+		Entry("update", http.MethodPut, "/api/v1/namespaces/myns"),
+		Entry("delete", http.MethodDelete, "/api/v1/namespaces/myns"),
+		Entry("patch", http.MethodPatch, "/api/v1/namespaces/myns"),
+	)
+
+	Context("admin endpoints", func() {
+		var s *namespacelister.NamespaceListerServer
+
+		BeforeEach(func() {
+			reader := fake.NewClientBuilder().WithLists(
+				&corev1.NamespaceList{Items: []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "myns"}}}},
+				&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "sar-creator"},
+						Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"authorization.k8s.io"}, Verbs: []string{"create"}, Resources: []string{"subjectaccessreviews"}}},
+					},
+				}},
+				&rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "sar-creator:admin"},
+						Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "admin"}},
+						RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "sar-creator"},
+					},
+				}},
+			).Build()
+
+			// the username is read from the request header
+			auth := AuthenticatorMock(func(r *http.Request) (*authenticator.Response, bool, error) {
+				return &authenticator.Response{User: &user.DefaultInfo{Name: r.Header.Get("Impersonate-User")}}, true, nil
+			})
+			s = namespacelister.NewServer(log, nil, lister, nil, auth)
+			s.HandleAdmin("GET /api/v1/namespaces/{name}/explain",
+				namespacelister.NewAuthorizer(context.TODO(), reader, log),
+				namespacelister.NewExplainHandler(log, namespacelister.NewExplainer(reader, log)))
+			s.HandleAdmin("GET /api/v1/namespaces/{name}/subjects",
+				namespacelister.NewAuthorizer(context.TODO(), reader, log),
+				namespacelister.NewSubjectsHandler(log, namespacelister.NewExplainer(reader, log)))
+		})
+
+		DescribeTable("explain", func(username, query string, expectedStatus int) {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/myns/explain"+query, nil)
+			r.Header.Add("Impersonate-User", username)
+
+			// when
+			s.Handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+			Expect(w.Result().Header.Get(namespacelister.HttpContentType)).To(Equal(namespacelister.HttpContentTypeApplication))
+		},
+			Entry("is served to admins", "admin", "?user=user&group=mygroup", http.StatusOK),
+			Entry("is forbidden to other users", "user", "?user=user&group=mygroup", http.StatusForbidden),
+			Entry("requires the user to explain", "admin", "", http.StatusBadRequest),
+		)
+
+		DescribeTable("subjects", func(username string, expectedStatus int) {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/myns/subjects", nil)
+			r.Header.Add("Impersonate-User", username)
+
+			// when
+			s.Handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+		},
+			Entry("is served to admins", "admin", http.StatusOK),
+			Entry("is forbidden to other users", "user", http.StatusForbidden),
+		)
+
+		It("returns NotFound if the namespace does not exist", func() {
+			// given
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/notfound/explain?user=user", nil)
+			r.Header.Add("Impersonate-User", "admin")
+
+			// when
+			s.Handler.ServeHTTP(w, r)
+
+			// then
+			Expect(w.Result().StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeStatus replies with the metav1.Status describing err, as kube-apiserver does.
+// Errors not carrying a Status are reported as Internal Server Errors.
+func writeStatus(l *slog.Logger, w http.ResponseWriter, err error) {
+	var s metav1.Status
+	if serr := kerrors.APIStatus(nil); errors.As(err, &serr) {
+		s = serr.Status()
+	} else {
+		s = kerrors.NewInternalError(err).Status()
+	}
+	s.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		l.Error("error marshaling status", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HttpContentType, HttpContentTypeApplication)
+	w.WriteHeader(int(s.Code))
+	if _, err := w.Write(b); err != nil {
+		l.Error("error writing reply", "error", err)
+	}
+}
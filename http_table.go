@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+const queryParamIncludeObject string = "includeObject"
+
+// namespaceColumns are the columns kube-apiserver uses for namespaces
+var namespaceColumns = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name", Description: metav1.ObjectMeta{}.SwaggerDoc()["name"]},
+	{Name: "Status", Type: "string", Description: "The status of the namespace"},
+	{Name: "Age", Type: "string", Description: metav1.ObjectMeta{}.SwaggerDoc()["creationTimestamp"]},
+}
+
+// TableOptions are the options of a request for a metav1.Table
+type TableOptions struct {
+	// IncludeObject is the representation of the namespace included in each row
+	IncludeObject metav1.IncludeObjectPolicy
+}
+
+// parseTableOptions reads the TableOptions from the request query parameters
+func parseTableOptions(q url.Values) (*TableOptions, error) {
+	opts := &TableOptions{IncludeObject: metav1.IncludeMetadata}
+	switch v := metav1.IncludeObjectPolicy(q.Get(queryParamIncludeObject)); v {
+	case "":
+	case metav1.IncludeNone, metav1.IncludeMetadata, metav1.IncludeObject:
+		opts.IncludeObject = v
+	default:
+		return nil, kerrors.NewBadRequest(fmt.Sprintf("%s %q is not valid, supported values are %s, %s, and %s",
+			queryParamIncludeObject, v, metav1.IncludeNone, metav1.IncludeMetadata, metav1.IncludeObject))
+	}
+	return opts, nil
+}
+
+// namespaceTable returns the namespaces as a metav1.Table with the list metadata
+func namespaceTable(nn *corev1.NamespaceList, opts *TableOptions) (*metav1.Table, error) {
+	t := &metav1.Table{
+		TypeMeta:          metav1.TypeMeta{Kind: KindTable, APIVersion: metav1.SchemeGroupVersion.String()},
+		ListMeta:          nn.ListMeta,
+		ColumnDefinitions: namespaceColumns,
+		Rows:              make([]metav1.TableRow, 0, len(nn.Items)),
+	}
+	now := time.Now()
+	for i := range nn.Items {
+		r, err := namespaceRow(&nn.Items[i], opts, now)
+		if err != nil {
+			return nil, err
+		}
+		t.Rows = append(t.Rows, r)
+	}
+	return t, nil
+}
+
+// namespaceRow returns the cells of the namespace and its representation requested in the options
+func namespaceRow(ns *corev1.Namespace, opts *TableOptions, now time.Time) (metav1.TableRow, error) {
+	age := "<unknown>"
+	if !ns.CreationTimestamp.IsZero() {
+		age = duration.HumanDuration(now.Sub(ns.CreationTimestamp.Time))
+	}
+	r := metav1.TableRow{Cells: []any{ns.Name, string(ns.Status.Phase), age}}
+
+	var obj any
+	switch opts.IncludeObject {
+	case metav1.IncludeNone:
+		return r, nil
+	case metav1.IncludeObject:
+		o := ns.DeepCopy()
+		o.TypeMeta = metav1.TypeMeta{Kind: "Namespace", APIVersion: corev1.SchemeGroupVersion.Version}
+		obj = o
+	default:
+		obj = partialObjectMetadata(ns)
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return r, err
+	}
+	r.Object = runtime.RawExtension{Raw: b}
+	return r, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/validation/path"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+const (
+	queryParamVerb          string = "verb"
+	queryParamResource      string = "resource"
+	queryParamGroup         string = "group"
+	queryParamRole          string = "role"
+	queryParamIncludeAccess string = "includeAccess"
+	queryParamLabelSelector string = "labelSelector"
+	queryParamFieldSelector string = "fieldSelector"
+)
+
+var (
+	// accessCheckVerbs are the verbs that can be checked on a namespace.
+	// Verbs granting privileges on RBAC or identities (e.g. `bind`, `escalate`, `impersonate`) are not allowed.
+	accessCheckVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+
+	// namespaceNameScopedVerbs are the verbs that apply to a single namespace object
+	namespaceNameScopedVerbs = sets.New("get", "update", "patch", "delete")
+
+	// clusterScopedResources are well-known cluster-scoped resources:
+	// access to them can not be granted in a namespace.
+	clusterScopedResources = sets.New(
+		"nodes", "persistentvolumes", "componentstatuses",
+		"clusterroles.rbac.authorization.k8s.io", "clusterrolebindings.rbac.authorization.k8s.io",
+		"customresourcedefinitions.apiextensions.k8s.io", "storageclasses.storage.k8s.io",
+		"priorityclasses.scheduling.k8s.io", "certificatesigningrequests.certificates.k8s.io",
+		"validatingwebhookconfigurations.admissionregistration.k8s.io", "mutatingwebhookconfigurations.admissionregistration.k8s.io",
+	)
+
+	// namespaceSelectableFields are the fields namespaces can be selected by, as in kube-apiserver
+	namespaceSelectableFields = sets.New("metadata.name", "status.phase")
+)
+
+// ListOptions are the options of a list request
+type ListOptions struct {
+	// Access is the access the user must have on a namespace for it to be listed
+	Access AccessCheck `json:"access"`
+	// Role, if set, restricts the namespaces to the ones in which the user
+	// is bound to the Role or ClusterRole with this name
+	Role string `json:"role,omitempty"`
+	// IncludeAccess requests to annotate each namespace with the verbs the user is allowed on
+	IncludeAccess bool `json:"includeAccess,omitempty"`
+	// LabelSelector and FieldSelector restrict the namespaces to the ones matching them.
+	// They are applied before evaluating the user access.
+	LabelSelector string `json:"labelSelector,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+}
+
+// selectors returns the label and field selectors of the options.
+// Empty selectors match every namespace.
+func (o ListOptions) selectors() (labels.Selector, fields.Selector, error) {
+	ls, err := labels.Parse(o.LabelSelector)
+	if err != nil {
+		return nil, nil, kerrors.NewBadRequest(fmt.Sprintf("invalid %s %q: %v", queryParamLabelSelector, o.LabelSelector, err))
+	}
+
+	fs, err := fields.ParseSelector(o.FieldSelector)
+	if err != nil {
+		return nil, nil, kerrors.NewBadRequest(fmt.Sprintf("invalid %s %q: %v", queryParamFieldSelector, o.FieldSelector, err))
+	}
+	for _, r := range fs.Requirements() {
+		if !namespaceSelectableFields.Has(r.Field) {
+			return nil, nil, kerrors.NewBadRequest(fmt.Sprintf("field label not supported: %s", r.Field))
+		}
+	}
+	return ls, fs, nil
+}
+
+// namespaceFields returns the selectable fields of the namespace
+func namespaceFields(ns *corev1.Namespace) fields.Set {
+	return fields.Set{
+		"metadata.name": ns.Name,
+		"status.phase":  string(ns.Status.Phase),
+	}
+}
+
+// AccessCheck is an access to a resource in a namespace.
+// Resource can include a subresource, e.g. `pods/exec`.
+type AccessCheck struct {
+	Verb     string `json:"verb"`
+	Resource string `json:"resource"`
+	APIGroup string `json:"group"`
+}
+
+// DefaultAccessCheck is the access required by default to list a namespace
+var DefaultAccessCheck = AccessCheck{Verb: "get", Resource: "namespaces", APIGroup: corev1.GroupName}
+
+// IsDefault returns whether the check is the DefaultAccessCheck
+func (a AccessCheck) IsDefault() bool {
+	return a == DefaultAccessCheck
+}
+
+// Attributes returns the authorization attributes for checking the access in the given namespace.
+// Checks on namespaces target the namespace itself.
+func (a AccessCheck) Attributes(namespace string) authorizer.AttributesRecord {
+	resource, subresource, _ := strings.Cut(a.Resource, "/")
+	attrs := authorizer.AttributesRecord{
+		Verb:            a.Verb,
+		Resource:        resource,
+		Subresource:     subresource,
+		APIGroup:        a.APIGroup,
+		Namespace:       namespace,
+		ResourceRequest: true,
+	}
+	if a.APIGroup == corev1.GroupName {
+		attrs.APIVersion = corev1.SchemeGroupVersion.Version
+		if resource == "namespaces" {
+			attrs.Name = namespace
+		}
+	}
+	return attrs
+}
+
+// validate rejects checks that are malformed or would not be meaningful in a namespace
+func (a AccessCheck) validate() error {
+	if !slices.Contains(accessCheckVerbs, a.Verb) {
+		return fmt.Errorf("verb %q is not allowed, supported verbs are %s", a.Verb, strings.Join(accessCheckVerbs, ", "))
+	}
+
+	if strings.Contains(a.Resource, "*") || strings.Contains(a.APIGroup, "*") {
+		return fmt.Errorf("wildcards are not allowed in resource %q and group %q", a.Resource, a.APIGroup)
+	}
+	resource, subresource, hasSubresource := strings.Cut(a.Resource, "/")
+	if !isResourceName(resource) || (hasSubresource && !isResourceName(subresource)) {
+		return fmt.Errorf("resource %q is not valid, it must be a lowercase resource name optionally followed by /<subresource>", a.Resource)
+	}
+	if a.APIGroup != corev1.GroupName && len(validation.IsDNS1123Subdomain(a.APIGroup)) > 0 {
+		return fmt.Errorf("group %q is not a valid API group", a.APIGroup)
+	}
+
+	qualified := resource
+	if a.APIGroup != corev1.GroupName {
+		qualified += "." + a.APIGroup
+	}
+	if clusterScopedResources.Has(qualified) {
+		return fmt.Errorf("resource %q is cluster-scoped, access to it can not be checked in a namespace", qualified)
+	}
+	if qualified == "namespaces" && !namespaceNameScopedVerbs.Has(a.Verb) {
+		return fmt.Errorf("verb %q on namespaces is cluster-scoped, supported verbs are %s", a.Verb, strings.Join(sets.List(namespaceNameScopedVerbs), ", "))
+	}
+	return nil
+}
+
+// isResourceName returns whether r is a valid resource or subresource name
+func isResourceName(r string) bool {
+	return len(validation.IsDNS1123Label(r)) == 0
+}
+
+// parseListOptions reads the ListOptions from the request query parameters.
+// The access check defaults to `get namespaces` and is validated, as the selectors are:
+// invalid options are reported as BadRequest errors.
+func parseListOptions(q url.Values) (ListOptions, error) {
+	a := DefaultAccessCheck
+	if q.Has(queryParamVerb) || q.Has(queryParamResource) || q.Has(queryParamGroup) {
+		a = AccessCheck{
+			Verb:     valueOrDefault(q.Get(queryParamVerb), DefaultAccessCheck.Verb),
+			Resource: valueOrDefault(q.Get(queryParamResource), DefaultAccessCheck.Resource),
+			APIGroup: q.Get(queryParamGroup),
+		}
+	}
+	if err := a.validate(); err != nil {
+		return ListOptions{}, kerrors.NewBadRequest(err.Error())
+	}
+
+	role := q.Get(queryParamRole)
+	if msgs := path.IsValidPathSegmentName(role); len(msgs) > 0 {
+		return ListOptions{}, kerrors.NewBadRequest(fmt.Sprintf("role %q is not valid: %s", role, strings.Join(msgs, ", ")))
+	}
+
+	includeAccess := false
+	if v := q.Get(queryParamIncludeAccess); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return ListOptions{}, kerrors.NewBadRequest(fmt.Sprintf("%s %q is not a boolean", queryParamIncludeAccess, v))
+		}
+		includeAccess = b
+	}
+
+	opts := ListOptions{
+		Access:        a,
+		Role:          role,
+		IncludeAccess: includeAccess,
+		LabelSelector: q.Get(queryParamLabelSelector),
+		FieldSelector: q.Get(queryParamFieldSelector),
+	}
+
+	// equivalent selectors share the same canonical form
+	ls, fs, err := opts.selectors()
+	if err != nil {
+		return ListOptions{}, err
+	}
+	opts.LabelSelector, opts.FieldSelector = ls.String(), fs.String()
+	return opts, nil
+}
+
+// valueOrDefault returns v, or defaultValue if v is empty
+func valueOrDefault(v, defaultValue string) string {
+	if v == "" {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	queryParamLimit    string = "limit"
+	queryParamContinue string = "continue"
+
+	// snapshotTTL is the time a client has to request the next page of a list
+	snapshotTTL = 5 * time.Minute
+	// maxSnapshots is the maximum number of lists being paginated
+	maxSnapshots = 1024
+	// maxSnapshotsPerUser is the maximum number of lists a user paginates at the same time
+	maxSnapshotsPerUser = 8
+)
+
+// PageOptions are the pagination options of a list request
+type PageOptions struct {
+	// Limit is the maximum number of namespaces to return, 0 means no limit
+	Limit int64
+	// Continue is the token returned with the previous page
+	Continue string
+}
+
+// parsePageOptions reads the PageOptions from the request query parameters
+func parsePageOptions(q url.Values) (PageOptions, error) {
+	opts := PageOptions{Continue: q.Get(queryParamContinue)}
+	if v := q.Get(queryParamLimit); v != "" {
+		l, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || l < 0 {
+			return opts, kerrors.NewBadRequest(fmt.Sprintf("%s %q is not a non-negative integer", queryParamLimit, v))
+		}
+		opts.Limit = l
+	}
+	return opts, nil
+}
+
+// continueToken identifies the position of the next page in a snapshot.
+// It is encoded as base64 JSON, and is opaque to clients.
+type continueToken struct {
+	Snapshot string `json:"snapshot"`
+	Start    int    `json:"start"`
+}
+
+// snapshotItem is a namespace of a paginated list.
+// Only its name, and the access summary computed for the user, are kept:
+// the namespace is read back from the cache when its page is requested.
+type snapshotItem struct {
+	name   string
+	access string
+}
+
+// listSnapshot is a list whose pages are being read
+type listSnapshot struct {
+	// key identifies the user and the list options the list was computed for
+	key string
+	// user is the name of the user the list was computed for
+	user     string
+	typeMeta metav1.TypeMeta
+	listMeta metav1.ListMeta
+	items    []snapshotItem
+	expires  time.Time
+}
+
+// listSnapshots keeps the paginated lists for the time clients read their pages:
+// all the pages of a list come from the same authorized result.
+// At most maxSnapshots lists are kept, and maxSnapshotsPerUser for each user:
+// the least recently used lists are evicted first.
+type listSnapshots struct {
+	reader client.Reader
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	snapshots *lru.Cache
+	// users are the identifiers of each user's snapshots, oldest first
+	users map[string][]string
+}
+
+func newListSnapshots(reader client.Reader, ttl time.Duration) *listSnapshots {
+	s := &listSnapshots{
+		reader: reader,
+		ttl:    ttl,
+		users:  map[string][]string{},
+	}
+	s.snapshots = lru.NewWithEvictionFunc(maxSnapshots, s.evictedLocked)
+	return s
+}
+
+// first returns the first page of the list, keeping the list for the following pages if needed
+func (s *listSnapshots) first(ui user.Info, opts ListOptions, nn *corev1.NamespaceList, limit int64) (*corev1.NamespaceList, error) {
+	if limit == 0 || int64(len(nn.Items)) <= limit {
+		return nn, nil
+	}
+
+	key, err := listCacheKey(ui, opts)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newSnapshotID()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &listSnapshot{
+		key:      key,
+		user:     ui.GetName(),
+		typeMeta: nn.TypeMeta,
+		listMeta: nn.ListMeta,
+		items:    make([]snapshotItem, 0, len(nn.Items)),
+		expires:  time.Now().Add(s.ttl),
+	}
+	for _, ns := range nn.Items {
+		it := snapshotItem{name: ns.Name}
+		if opts.IncludeAccess {
+			it.access = ns.Annotations[AnnotationAccess]
+		}
+		snap.items = append(snap.items, it)
+	}
+
+	s.mu.Lock()
+	if ids := s.users[snap.user]; len(ids) >= maxSnapshotsPerUser {
+		s.snapshots.Remove(ids[0])
+	}
+	s.snapshots.Add(id, snap)
+	s.users[snap.user] = append(s.users[snap.user], id)
+	s.mu.Unlock()
+
+	end, meta, err := pageMeta(nn.ListMeta, id, 0, len(nn.Items), limit)
+	if err != nil {
+		return nil, err
+	}
+	p := *nn
+	p.ListMeta = meta
+	p.Items = nn.Items[:end]
+	return &p, nil
+}
+
+// next returns the page identified by the continue token.
+// The namespaces are read from the cache: the ones deleted since the first page are skipped.
+func (s *listSnapshots) next(ctx context.Context, ui user.Info, opts ListOptions, popts PageOptions) (*corev1.NamespaceList, error) {
+	t, err := decodeContinueToken(popts.Continue)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	snap, ok := s.getLocked(t.Snapshot, time.Now())
+	s.mu.Unlock()
+	if !ok {
+		return nil, kerrors.NewResourceExpired("the provided continue parameter is too old to display a consistent list result, start a new list without the continue parameter")
+	}
+
+	// the token can only be continued by the same user, with the same options
+	key, err := listCacheKey(ui, opts)
+	if err != nil {
+		return nil, err
+	}
+	if key != snap.key || t.Start > len(snap.items) {
+		return nil, kerrors.NewBadRequest("continue key is not valid for this request")
+	}
+
+	end, meta, err := pageMeta(snap.listMeta, t.Snapshot, t.Start, len(snap.items), popts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.NamespaceList{TypeMeta: snap.typeMeta, ListMeta: meta, Items: make([]corev1.Namespace, 0, end-t.Start)}
+	for _, it := range snap.items[t.Start:end] {
+		ns, err := getNamespace(ctx, s.reader, it.name)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if it.access != "" {
+			if ns.Annotations == nil {
+				ns.Annotations = map[string]string{}
+			}
+			ns.Annotations[AnnotationAccess] = it.access
+		}
+		p.Items = append(p.Items, *ns)
+	}
+	return p, nil
+}
+
+// getLocked returns the snapshot with the given identifier, removing it if expired. s.mu must be held
+func (s *listSnapshots) getLocked(id string, now time.Time) (*listSnapshot, bool) {
+	v, ok := s.snapshots.Get(id)
+	if !ok {
+		return nil, false
+	}
+	snap := v.(*listSnapshot)
+	if now.After(snap.expires) {
+		s.snapshots.Remove(id)
+		return nil, false
+	}
+	return snap, true
+}
+
+// evictedLocked forgets the evicted snapshot from its user's ones. s.mu must be held
+func (s *listSnapshots) evictedLocked(key lru.Key, value any) {
+	snap := value.(*listSnapshot)
+	ids := slices.DeleteFunc(s.users[snap.user], func(id string) bool { return id == key })
+	if len(ids) == 0 {
+		delete(s.users, snap.user)
+		return
+	}
+	s.users[snap.user] = ids
+}
+
+// pageMeta returns the end of the page of up to limit items starting at start,
+// and its list metadata with the continue token for the remaining ones
+func pageMeta(meta metav1.ListMeta, snapshot string, start, total int, limit int64) (int, metav1.ListMeta, error) {
+	end := total
+	if limit > 0 && int64(end-start) > limit {
+		end = start + int(limit)
+	}
+
+	meta.Continue, meta.RemainingItemCount = "", nil
+	if remaining := int64(total - end); remaining > 0 {
+		c, err := encodeContinueToken(continueToken{Snapshot: snapshot, Start: end})
+		if err != nil {
+			return 0, meta, err
+		}
+		meta.Continue, meta.RemainingItemCount = c, &remaining
+	}
+	return end, meta, nil
+}
+
+func newSnapshotID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func encodeContinueToken(t continueToken) (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeContinueToken(c string) (continueToken, error) {
+	t := continueToken{}
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil || json.Unmarshal(b, &t) != nil || t.Snapshot == "" || t.Start < 0 {
+		return t, kerrors.NewBadRequest("continue key is not valid")
+	}
+	return t, nil
+}
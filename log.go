@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+
+	"k8s.io/apiserver/pkg/authentication/user"
 )
 
 // buildLogger constructs a new instance of the logger
@@ -25,3 +27,13 @@ func getLogLevel() slog.Level {
 	}
 	return slog.Level(level)
 }
+
+// userLogAttr returns the user identity as a log attribute
+func userLogAttr(ui user.Info) slog.Attr {
+	return slog.Group("user",
+		"name", ui.GetName(),
+		"uid", ui.GetUID(),
+		"groups", ui.GetGroups(),
+		"extra", ui.GetExtra(),
+	)
+}
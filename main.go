@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 
 	"github.com/go-logr/logr"
 
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -32,16 +37,134 @@ func run(l *slog.Logger) error {
 		return err
 	}
 
-	// create the authorizer and the namespace lister
-	auth := NewAuthorizer(ctx, cache, l)
-	nsl := NewNamespaceLister(cache, auth, l)
+	// changes of the cached resources invalidate the access index and the decision cache
+	// before being notified to the watchers
+	changes := NewChangeNotifier()
+	if err := NotifyOnChange(ctx, cache, changes.Notify); err != nil {
+		return err
+	}
+
+	// the access index is only built on its first use, then RoleBindings changes are applied to it
+	// and it is rebuilt on the first use after any other change
+	idx := NewAccessIndex(ctx, cache, l)
+	changes.OnChange(idx.Update)
+
+	// create the namespace lister
+	nsl, err := buildNamespaceLister(ctx, cache, idx, &changes.Generation, l)
+	if err != nil {
+		return err
+	}
+
+	// build the authenticator
+	l.Info("building authenticator", "mode", getAuthMode())
+	authn, err := buildAuthenticator()
+	if err != nil {
+		return err
+	}
 
 	// build http server
 	l.Info("building server")
-	userHeader := getHeaderUsername()
-	s := NewServer(l, nsl, userHeader)
+	s := NewServer(l, cache, nsl, changes, authn)
+	authz := NewAuthorizer(ctx, cache, l)
+	s.Handle(patternGetNamespace, NewGetNamespaceHandler(l, cache, authz))
+	explainer := NewExplainer(cache, l)
+	s.HandleAdmin(patternGetExplain, authz, NewExplainHandler(l, explainer))
+	s.HandleAdmin(patternGetSubjects, authz, NewSubjectsHandler(l, explainer))
+	s.HandleAdmin(patternGetMatrix, authz, NewAccessMatrixHandler(l, idx))
+	if certFile, keyFile := getTLSFiles(); certFile != "" || keyFile != "" {
+		s.EnableTLS(certFile, keyFile)
+	} else if getRequestHeaderClientCAFile() != "" {
+		return fmt.Errorf("%s requires %s and %s to be set", EnvRequestHeaderClientCAFile, EnvTLSCertFile, EnvTLSKeyFile)
+	}
 
 	// start the server
 	l.Info("serving...")
 	return s.Start(ctx)
 }
+
+// buildNamespaceLister builds the namespace lister.
+// The given AccessIndex is used if enabled, the decision cache is invalidated when the Generation changes.
+func buildNamespaceLister(ctx context.Context, c cache.Cache, idx *AccessIndex, gen *Generation, l *slog.Logger) (NamespaceLister, error) {
+	indexEnabled, err := getAccessIndexEnabled()
+	if err != nil {
+		return nil, err
+	}
+	decisionCacheEnabled, err := getDecisionCacheEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := getNamespaceListerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// the authorizer-based lister answers the access checks not covered by the index
+	auth := NewAuthorizer(ctx, c, l)
+	nsl := NewNamespaceLister(c, auth, opts, l)
+	if indexEnabled {
+		nsl = NewIndexedNamespaceLister(c, idx, nsl, l)
+	} else {
+		l.Info("access index disabled, authorizing the user on every namespace", "workers", opts.Workers, "timeout", opts.Timeout)
+	}
+
+	// restricts the namespaces to the ones the user is bound to the requested role in
+	nsl = NewRoleNamespaceLister(c, nsl, l)
+
+	if decisionCacheEnabled {
+		nsl = NewCachedNamespaceLister(c, nsl, gen, l)
+	}
+
+	// the access summary is computed on the user's namespaces, whether they come from the cache or not
+	resources, err := getAccessResources()
+	if err != nil {
+		return nil, err
+	}
+	nsl = NewAccessSummaryNamespaceLister(c, nsl, resources, l)
+
+	// concurrent identical requests share a single evaluation
+	return NewSingleflightNamespaceLister(nsl), nil
+}
+
+// buildAuthenticator builds the authenticator for the configured authentication mode
+func buildAuthenticator() (authenticator.Request, error) {
+	switch mode := getAuthMode(); mode {
+	case AuthModeHeader:
+		auth := NewHeaderAuthenticator(getUserHeaders())
+		caFile := getRequestHeaderClientCAFile()
+		if caFile == "" {
+			return auth, nil
+		}
+
+		// trust the headers only if the request comes from the front-proxy
+		clientCAs, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewFrontProxyAuthenticator(clientCAs, getRequestHeaderAllowedNames(), auth), nil
+	case AuthModeTokenReview:
+		ttl, err := getTokenReviewCacheTTL()
+		if err != nil {
+			return nil, err
+		}
+
+		cli, err := kubernetes.NewForConfig(ctrl.GetConfigOrDie())
+		if err != nil {
+			return nil, err
+		}
+		return NewTokenReviewAuthenticator(cli.AuthenticationV1().TokenReviews(), ttl), nil
+	case AuthModeOIDC:
+		jwks := getOIDCJWKS()
+		if jwks == "" {
+			return nil, fmt.Errorf("%s is required in %s mode", EnvOIDCJWKS, AuthModeOIDC)
+		}
+
+		p, err := newJWKSProvider(jwks)
+		if err != nil {
+			return nil, err
+		}
+		return NewOIDCAuthenticator(getOIDCOptions(), p)
+	default:
+		return nil, fmt.Errorf("invalid %s %q: supported values are %q, %q, and %q", EnvAuthMode, mode, AuthModeHeader, AuthModeTokenReview, AuthModeOIDC)
+	}
+}
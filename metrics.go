@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// listRequestsTotal counts the list requests, partitioned by whether
+	// they were coalesced with a concurrent identical request
+	listRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "namespace_lister",
+			Name:      "list_requests_total",
+			Help:      "Number of list requests, partitioned by whether they were coalesced with a concurrent identical request.",
+		},
+		[]string{"coalesced"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(listRequestsTotal)
+}
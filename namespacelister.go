@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
@@ -15,26 +21,145 @@ import (
 var _ NamespaceLister = &namespaceLister{}
 
 type NamespaceLister interface {
-	ListNamespaces(ctx context.Context, username string) (*corev1.NamespaceList, error)
+	ListNamespaces(ctx context.Context, user user.Info, opts ListOptions) (*corev1.NamespaceList, error)
+}
+
+// NamespaceListerOptions configures the evaluation of the user access to namespaces
+type NamespaceListerOptions struct {
+	// Workers is the number of namespaces evaluated in parallel
+	Workers int
+	// Timeout bounds the time spent evaluating a request, no limit if zero
+	Timeout time.Duration
 }
 
 type namespaceLister struct {
 	client.Reader
 
 	authorizer *rbac.RBACAuthorizer
+	opts       NamespaceListerOptions
 	l          *slog.Logger
 }
 
-func NewNamespaceLister(reader client.Reader, authorizer *rbac.RBACAuthorizer, l *slog.Logger) NamespaceLister {
+func NewNamespaceLister(reader client.Reader, authorizer *rbac.RBACAuthorizer, opts NamespaceListerOptions, l *slog.Logger) NamespaceLister {
+	opts.Workers = max(opts.Workers, 1)
 	return &namespaceLister{
 		Reader:     reader,
 		authorizer: authorizer,
+		opts:       opts,
 		l:          l,
 	}
 }
 
-func (c *namespaceLister) ListNamespaces(ctx context.Context, username string) (*corev1.NamespaceList, error) {
+func (c *namespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
 	// list all namespaces
+	nn, err := listNamespaces(ctx, c.Reader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	l := c.l.With(userLogAttr(ui), "access", opts.Access)
+
+	// users granted access to every namespace do not need to be authorized on each of them
+	if c.hasClusterWideAccess(ctx, ui, opts.Access) {
+		l.Info("user has cluster-wide access to namespaces", "namespaces", len(nn.Items))
+		return nn, nil
+	}
+
+	rnn, err := c.authorizedNamespaces(ctx, l, ui, opts.Access, nn.Items)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, kerrors.NewTimeoutError("evaluating user access to namespaces", 0)
+		}
+		return nil, err
+	}
+	nn.Items = rnn
+
+	return nn, nil
+}
+
+// authorizedNamespaces returns the namespaces in which the user has the given access, preserving their order.
+// Namespaces are evaluated in parallel by a bounded number of workers.
+// The evaluation stops as soon as the context is done.
+func (c *namespaceLister) authorizedNamespaces(ctx context.Context, l *slog.Logger, ui user.Info, access AccessCheck, nn []corev1.Namespace) ([]corev1.Namespace, error) {
+	allowed := make([]bool, len(nn))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.opts.Workers)
+	for i, ns := range nn {
+		if gctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+
+			attrs := access.Attributes(ns.Name)
+			attrs.User = ui
+			d, _, err := c.authorizer.Authorize(gctx, attrs)
+			if err != nil {
+				return err
+			}
+
+			l.Info("evaluated user access to namespace", "namespace", ns.Name, "decision", d)
+			allowed[i] = d == authorizer.DecisionAllow
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	// the loop may have stopped before all the namespaces were evaluated
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rnn := []corev1.Namespace{}
+	for i, ns := range nn {
+		if allowed[i] {
+			rnn = append(rnn, ns)
+		}
+	}
+	return rnn, nil
+}
+
+// hasClusterWideAccess returns whether a ClusterRoleBinding grants the user the given access
+// in every namespace
+func (c *namespaceLister) hasClusterWideAccess(ctx context.Context, ui user.Info, access AccessCheck) bool {
+	// with no namespace, only the rules granted by ClusterRoleBindings are resolved
+	rr, err := NewRuleResolver(ctx, c.Reader, c.l).RulesFor(ui, "")
+	if err != nil {
+		c.l.Debug("error resolving cluster-wide rules", userLogAttr(ui), "error", err)
+	}
+	return rbac.RulesAllow(access.Attributes(""), rr...)
+}
+
+// getNamespace returns the namespace with the given name.
+// The cache reports a missing namespace on its Kind, it is reported on the namespaces resource as kube-apiserver does.
+func getNamespace(ctx context.Context, reader client.Reader, name string) (*corev1.Namespace, error) {
+	ns := corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: name}, &ns); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, kerrors.NewNotFound(corev1.Resource("namespaces"), name)
+		}
+		return nil, err
+	}
+	return &ns, nil
+}
+
+// listNamespaces lists the namespaces matching the selectors of the list options, sorted by name
+func listNamespaces(ctx context.Context, reader client.Reader, opts ListOptions) (*corev1.NamespaceList, error) {
+	ls, fs, err := opts.selectors()
+	if err != nil {
+		return nil, err
+	}
+
 	nn := corev1.NamespaceList{
 		TypeMeta: metav1.TypeMeta{
 			// even though `kubectl get namespaces -o yaml` is showing `kind: List`
@@ -44,32 +169,18 @@ func (c *namespaceLister) ListNamespaces(ctx context.Context, username string) (
 			APIVersion: corev1.SchemeGroupVersion.Version,
 		},
 	}
-	if err := c.List(ctx, &nn); err != nil {
+	if err := reader.List(ctx, &nn, client.MatchingLabelsSelector{Selector: ls}); err != nil {
 		return nil, err
 	}
 
-	rnn := []corev1.Namespace{}
-	for _, ns := range nn.Items {
-		d, _, err := c.authorizer.Authorize(ctx, authorizer.AttributesRecord{
-			User:            &user.DefaultInfo{Name: username},
-			Verb:            "get",
-			Resource:        "namespaces",
-			APIGroup:        corev1.GroupName,
-			APIVersion:      corev1.SchemeGroupVersion.Version,
-			Name:            ns.Name,
-			Namespace:       ns.Name,
-			ResourceRequest: true,
+	// the cache only supports field selectors backed by an index, fields are matched here instead
+	if !fs.Empty() {
+		nn.Items = slices.DeleteFunc(nn.Items, func(ns corev1.Namespace) bool {
+			return !fs.Matches(namespaceFields(&ns))
 		})
-		if err != nil {
-			return nil, err
-		}
-
-		c.l.Info("evaluated user access to namespace", "namespace", ns.Name, "user", username, "decision", d)
-		if d == authorizer.DecisionAllow {
-			rnn = append(rnn, ns)
-		}
 	}
-	nn.Items = rnn
 
+	// the cache lists the namespaces in a random order, they are sorted by name as kube-apiserver does
+	slices.SortFunc(nn.Items, func(a, b corev1.Namespace) int { return strings.Compare(a.Name, b.Name) })
 	return &nn, nil
 }
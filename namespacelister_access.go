@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ NamespaceLister = &accessSummaryNamespaceLister{}
+
+// accessSummaryNamespaceLister annotates the namespaces returned by the wrapped lister
+// with the verbs the user is allowed on the configured resources, if requested in the ListOptions.
+// The verbs are computed from the same in-memory RBAC rules used for listing the namespaces.
+type accessSummaryNamespaceLister struct {
+	client.Reader
+
+	lister    NamespaceLister
+	resources []schema.GroupResource
+	l         *slog.Logger
+}
+
+func NewAccessSummaryNamespaceLister(reader client.Reader, lister NamespaceLister, resources []schema.GroupResource, l *slog.Logger) NamespaceLister {
+	return &accessSummaryNamespaceLister{
+		Reader:    reader,
+		lister:    lister,
+		resources: resources,
+		l:         l,
+	}
+}
+
+func (c *accessSummaryNamespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	nn, err := c.lister.ListNamespaces(ctx, ui, opts)
+	if err != nil || !opts.IncludeAccess {
+		return nn, err
+	}
+
+	// the rules granted by ClusterRoleBindings are resolved once,
+	// only the ones granted by RoleBindings are resolved for each namespace.
+	// Rules resolution errors are ignored, as the RBAC authorizer does
+	crr, err := NewRuleResolver(ctx, c.Reader, c.l).RulesFor(ui, "")
+	if err != nil {
+		c.l.Debug("error resolving cluster rules", userLogAttr(ui), "error", err)
+	}
+	resolver := NewRoleBindingRuleResolver(ctx, c.Reader, c.l)
+	for i := range nn.Items {
+		ns := &nn.Items[i]
+
+		rbr, err := resolver.RulesFor(ui, ns.Name)
+		if err != nil {
+			c.l.Debug("error resolving rules", userLogAttr(ui), "namespace", ns.Name, "error", err)
+		}
+		rr := slices.Concat(crr, rbr)
+
+		access := make(map[string][]string, len(c.resources))
+		for _, gr := range c.resources {
+			verbs := []string{}
+			for _, v := range accessCheckVerbs {
+				a := AccessCheck{Verb: v, Resource: gr.Resource, APIGroup: gr.Group}
+				if rbac.RulesAllow(a.Attributes(ns.Name), rr...) {
+					verbs = append(verbs, v)
+				}
+			}
+			access[gr.String()] = verbs
+		}
+
+		b, err := json.Marshal(access)
+		if err != nil {
+			return nil, err
+		}
+		ns.Annotations = maps.Clone(ns.Annotations)
+		if ns.Annotations == nil {
+			ns.Annotations = map[string]string{}
+		}
+		ns.Annotations[AnnotationAccess] = string(b)
+	}
+	return nn, nil
+}
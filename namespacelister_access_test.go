@@ -0,0 +1,114 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("AccessSummaryNamespaceLister", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+		ui     = &user.DefaultInfo{Name: "user", Groups: []string{"mygroup"}}
+
+		nsl      namespacelister.NamespaceLister
+		crbLists int
+	)
+
+	BeforeEach(func() {
+		crbLists = 0
+		reader := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if _, ok := list.(*rbacv1.ClusterRoleBindingList); ok {
+					crbLists++
+				}
+				return c.List(ctx, list, opts...)
+			},
+		}).WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1", Annotations: map[string]string{"existing": "annotation"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get", "list"}, Resources: []string{"pods"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "deployment-lister"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Verbs: []string{"list"}, Resources: []string{"deployments"}}},
+				},
+			}},
+			&rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "deployment-lister:mygroup"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "mygroup"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "deployment-lister"},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-reader:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "pod-reader"},
+				},
+			}},
+		).Build()
+
+		// the wrapped lister returns all the namespaces
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			nn := corev1.NamespaceList{}
+			Expect(reader.List(ctx, &nn)).To(Succeed())
+			return &nn, nil
+		})
+		nsl = namespacelister.NewAccessSummaryNamespaceLister(reader, lister, []schema.GroupResource{
+			{Resource: "pods"},
+			{Group: "apps", Resource: "deployments"},
+		}, logger)
+	})
+
+	It("annotates the namespaces with the user's effective verbs", func() {
+		// when
+		nn, err := nsl.ListNamespaces(ctx, ui, namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, IncludeAccess: true})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nn.Items).To(HaveLen(2))
+		Expect(nn.Items[0].Annotations).To(Equal(map[string]string{
+			"existing":                       "annotation",
+			namespacelister.AnnotationAccess: `{"deployments.apps":["list"],"pods":["get","list"]}`,
+		}))
+		Expect(nn.Items[1].Annotations).To(Equal(map[string]string{
+			namespacelister.AnnotationAccess: `{"deployments.apps":["list"],"pods":[]}`,
+		}))
+		// the ClusterRoleBindings are resolved once for all the namespaces
+		Expect(crbLists).To(Equal(1))
+	})
+
+	It("does not annotate the namespaces if not requested", func() {
+		// when
+		nn, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nn.Items).To(HaveLen(2))
+		Expect(nn.Items[0].Annotations).NotTo(HaveKey(namespacelister.AnnotationAccess))
+		Expect(nn.Items[1].Annotations).NotTo(HaveKey(namespacelister.AnnotationAccess))
+	})
+})
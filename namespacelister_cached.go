@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// decisionCacheSize is the maximum number of user identities and list options cached.
+// List options are chosen by the clients, the least recently used entries are evicted.
+const decisionCacheSize = 1024
+
+var _ NamespaceLister = &cachedNamespaceLister{}
+
+// cachedNamespaceLister caches, for each user identity and list options, the names of the namespaces
+// returned by the wrapped lister.
+// The whole cache is invalidated as soon as the Generation changes,
+// and holds at most decisionCacheSize entries.
+type cachedNamespaceLister struct {
+	client.Reader
+
+	lister     NamespaceLister
+	generation *Generation
+	l          *slog.Logger
+
+	mu sync.Mutex
+	// entriesGeneration is the generation the entries were computed at
+	entriesGeneration uint64
+	entries           *lru.Cache
+}
+
+func NewCachedNamespaceLister(reader client.Reader, lister NamespaceLister, generation *Generation, l *slog.Logger) NamespaceLister {
+	return &cachedNamespaceLister{
+		Reader:            reader,
+		lister:            lister,
+		generation:        generation,
+		l:                 l,
+		entriesGeneration: generation.Get(),
+		entries:           lru.New(decisionCacheSize),
+	}
+}
+
+func (c *cachedNamespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	key, err := listCacheKey(ui, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	g := c.generation.Get()
+	if names, ok := c.get(g, key); ok {
+		c.l.Debug("namespace list cache hit", userLogAttr(ui), "generation", g)
+		return c.listNamed(ctx, names, opts)
+	}
+
+	nn, err := c.lister.ListNamespaces(ctx, ui, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	names := sets.New[string]()
+	for _, ns := range nn.Items {
+		names.Insert(ns.Name)
+	}
+	c.set(g, key, names)
+	return nn, nil
+}
+
+// get returns the entry for key, if it was computed at generation g.
+// The entries are discarded when g is newer than them, a request that read an older generation only misses.
+func (c *cachedNamespaceLister) get(g uint64, key string) (sets.Set[string], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if g > c.entriesGeneration {
+		c.entriesGeneration = g
+		c.entries.Clear()
+		return nil, false
+	}
+	if g < c.entriesGeneration {
+		return nil, false
+	}
+
+	names, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return names.(sets.Set[string]), true
+}
+
+// set stores the entry for key, unless the entries have moved past generation g
+func (c *cachedNamespaceLister) set(g uint64, key string, names sets.Set[string]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if g > c.entriesGeneration {
+		c.entriesGeneration = g
+		c.entries.Clear()
+	}
+	if c.entriesGeneration == g {
+		c.entries.Add(key, names)
+	}
+}
+
+// listNamed lists the namespaces with the given names matching the list options' selectors
+func (c *cachedNamespaceLister) listNamed(ctx context.Context, names sets.Set[string], opts ListOptions) (*corev1.NamespaceList, error) {
+	nn, err := listNamespaces(ctx, c.Reader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rnn := []corev1.Namespace{}
+	for _, ns := range nn.Items {
+		if names.Has(ns.Name) {
+			rnn = append(rnn, ns)
+		}
+	}
+	nn.Items = rnn
+	return nn, nil
+}
+
+// listCacheKey returns a key identifying the full user identity and the list options.
+// Groups are sorted, as their order does not affect the authorization.
+func listCacheKey(ui user.Info, opts ListOptions) (string, error) {
+	groups := slices.Clone(ui.GetGroups())
+	slices.Sort(groups)
+
+	b, err := json.Marshal(struct {
+		Name   string              `json:"name"`
+		UID    string              `json:"uid"`
+		Groups []string            `json:"groups"`
+		Extra  map[string][]string `json:"extra"`
+		Opts   ListOptions         `json:"opts"`
+	}{ui.GetName(), ui.GetUID(), groups, ui.GetExtra(), opts})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,145 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("CachedNamespaceLister", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+		calls      int
+		generation *namespacelister.Generation
+		nsl        namespacelister.NamespaceLister
+	)
+
+	BeforeEach(func() {
+		nn := corev1.NamespaceList{Items: []corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+		}}
+		reader := fake.NewClientBuilder().WithLists(&nn).Build()
+
+		calls = 0
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			calls++
+			nn := corev1.NamespaceList{}
+			Expect(reader.List(ctx, &nn)).To(Succeed())
+			nn.Items = nn.Items[:1]
+			return &nn, nil
+		})
+		generation = &namespacelister.Generation{}
+		nsl = namespacelister.NewCachedNamespaceLister(reader, lister, generation, logger)
+	})
+
+	It("reuses the result for the same user", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user", Groups: []string{"group-1", "group-2"}, Extra: map[string][]string{"scopes": {"openid"}}}
+		expected, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		nn, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user", Groups: []string{"group-2", "group-1"}, Extra: map[string][]string{"scopes": {"openid"}}}, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nn).To(Equal(expected))
+		Expect(nn.Items).To(HaveLen(1))
+		Expect(calls).To(Equal(1))
+	})
+
+	DescribeTable("does not reuse the result for a different identity", func(other user.Info) {
+		// given
+		_, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user", Groups: []string{"group"}, Extra: map[string][]string{"scopes": {"openid"}}}, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		_, err = nsl.ListNamespaces(ctx, other, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	},
+		Entry("different name", &user.DefaultInfo{Name: "other", Groups: []string{"group"}, Extra: map[string][]string{"scopes": {"openid"}}}),
+		Entry("different groups", &user.DefaultInfo{Name: "user", Groups: []string{"other"}, Extra: map[string][]string{"scopes": {"openid"}}}),
+		Entry("different extra", &user.DefaultInfo{Name: "user", Groups: []string{"group"}, Extra: map[string][]string{"scopes": {"email"}}}),
+	)
+
+	It("does not reuse the result for a different access check", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user"}
+		_, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		_, err = nsl.ListNamespaces(ctx, ui, namespacelister.ListOptions{Access: namespacelister.AccessCheck{Verb: "list", Resource: "secrets"}})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("does not reuse the result for different selectors", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user"}
+		_, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		_, err = nsl.ListNamespaces(ctx, ui, namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, LabelSelector: "konflux.ci/type=user"})
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("evicts the least recently used results", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user"}
+		_, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+		for i := range 1024 {
+			opts := namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, LabelSelector: fmt.Sprintf("key=value-%d", i)}
+			_, err := nsl.ListNamespaces(ctx, ui, opts)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(calls).To(Equal(1025))
+
+		// when
+		_, err = nsl.ListNamespaces(ctx, ui, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(1026))
+	})
+
+	It("invalidates the results when the generation changes", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user"}
+		_, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		generation.Inc()
+		_, err = nsl.ListNamespaces(ctx, ui, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+})
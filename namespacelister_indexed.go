@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ NamespaceLister = &indexedNamespaceLister{}
+
+// indexedNamespaceLister answers list requests from the AccessIndex
+// instead of authorizing the user on every namespace.
+// The index only covers the DefaultAccessCheck, other checks are delegated to the fallback lister.
+type indexedNamespaceLister struct {
+	client.Reader
+
+	index    *AccessIndex
+	fallback NamespaceLister
+	l        *slog.Logger
+}
+
+func NewIndexedNamespaceLister(reader client.Reader, index *AccessIndex, fallback NamespaceLister, l *slog.Logger) NamespaceLister {
+	return &indexedNamespaceLister{
+		Reader:   reader,
+		index:    index,
+		fallback: fallback,
+		l:        l,
+	}
+}
+
+func (c *indexedNamespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	if !opts.Access.IsDefault() {
+		if c.fallback == nil {
+			return nil, kerrors.NewBadRequest("only the default access check is supported")
+		}
+		return c.fallback.ListNamespaces(ctx, ui, opts)
+	}
+
+	// list all namespaces
+	nn, err := listNamespaces(ctx, c.Reader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// retrieve the namespaces the user has access to
+	a, err := c.index.NamespacesFor(ctx, ui)
+	if err != nil {
+		return nil, err
+	}
+
+	rnn := []corev1.Namespace{}
+	for _, ns := range nn.Items {
+		if a.Has(ns.Name) {
+			rnn = append(rnn, ns)
+		}
+	}
+	nn.Items = rnn
+
+	c.l.Info("evaluated user access to namespaces", userLogAttr(ui), "namespaces", len(rnn))
+	return nn, nil
+}
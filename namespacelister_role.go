@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ NamespaceLister = &roleNamespaceLister{}
+
+// roleNamespaceLister restricts the namespaces returned by the wrapped lister
+// to the ones in which the user is bound to the Role or ClusterRole requested in the ListOptions.
+type roleNamespaceLister struct {
+	client.Reader
+
+	lister NamespaceLister
+	l      *slog.Logger
+}
+
+func NewRoleNamespaceLister(reader client.Reader, lister NamespaceLister, l *slog.Logger) NamespaceLister {
+	return &roleNamespaceLister{
+		Reader: reader,
+		lister: lister,
+		l:      l,
+	}
+}
+
+func (c *roleNamespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	nn, err := c.lister.ListNamespaces(ctx, ui, opts)
+	if err != nil || opts.Role == "" {
+		return nn, err
+	}
+
+	a, err := c.namespacesBoundToRole(ctx, ui, opts.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	rnn := []corev1.Namespace{}
+	for _, ns := range nn.Items {
+		if a.Has(ns.Name) {
+			rnn = append(rnn, ns)
+		}
+	}
+	nn.Items = rnn
+
+	c.l.Debug("filtered namespaces by role", userLogAttr(ui), "role", opts.Role, "namespaces", len(rnn))
+	return nn, nil
+}
+
+// namespacesBoundToRole returns the namespaces in which a binding refers to the given role
+// and has the user, or one of its groups, as subject.
+// A ClusterRoleBinding binds the user to the ClusterRole in every namespace.
+func (c *roleNamespaceLister) namespacesBoundToRole(ctx context.Context, ui user.Info, role string) (namespaceAccess, error) {
+	aur := NewCRAuthRetriever(ctx, c.Reader, c.l)
+	subjects := sets.New(userIndexSubjects(ui)...)
+	appliesToUser := func(ss []rbacv1.Subject, bindingNamespace string) bool {
+		for _, s := range ss {
+			if is, ok := newIndexSubject(s, bindingNamespace); ok && subjects.Has(is) {
+				return true
+			}
+		}
+		return false
+	}
+
+	crbb, err := aur.ListClusterRoleBindings()
+	if err != nil {
+		return namespaceAccess{}, err
+	}
+	for _, crb := range crbb {
+		if crb.RoleRef.Kind == "ClusterRole" && crb.RoleRef.Name == role && appliesToUser(crb.Subjects, "") {
+			return namespaceAccess{all: true}, nil
+		}
+	}
+
+	// with no namespace, the RoleBindings in all the namespaces are listed
+	rbb, err := aur.ListRoleBindings("")
+	if err != nil {
+		return namespaceAccess{}, err
+	}
+	a := namespaceAccess{names: sets.New[string]()}
+	for _, rb := range rbb {
+		if rb.RoleRef.Name == role && appliesToUser(rb.Subjects, rb.Namespace) {
+			a.names.Insert(rb.Namespace)
+		}
+	}
+	return a, nil
+}
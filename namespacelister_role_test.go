@@ -0,0 +1,108 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("RoleNamespaceLister", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+		ui     = &user.DefaultInfo{Name: "user", Groups: []string{"mygroup"}}
+
+		reader client.Client
+		nsl    namespacelister.NamespaceLister
+	)
+
+	listedNames := func(role string) []string {
+		nn, err := nsl.ListNamespaces(ctx, ui, namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, Role: role})
+		Expect(err).NotTo(HaveOccurred())
+		names := []string{}
+		for _, ns := range nn.Items {
+			names = append(names, ns.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		reader = fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-3"}},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "admin:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "admin"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "admin:mygroup", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "mygroup"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "admin"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "view:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "view"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "admin:other", Namespace: "myns-3"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "other"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "admin"},
+				},
+			}},
+		).Build()
+
+		// the wrapped lister returns all the namespaces
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			nn := corev1.NamespaceList{}
+			Expect(reader.List(ctx, &nn)).To(Succeed())
+			return &nn, nil
+		})
+		nsl = namespacelister.NewRoleNamespaceLister(reader, lister, logger)
+	})
+
+	It("does not filter the namespaces if no role is requested", func() {
+		Expect(listedNames("")).To(ConsistOf("myns-1", "myns-2", "myns-3"))
+	})
+
+	DescribeTable("returns the namespaces the user is bound to the role in", func(role string, expected []string) {
+		Expect(listedNames(role)).To(ConsistOf(expected))
+	},
+		Entry("directly or through a group", "admin", []string{"myns-1", "myns-2"}),
+		Entry("directly", "view", []string{"myns-2"}),
+		Entry("not bound", "edit", []string{}),
+	)
+
+	It("returns all the namespaces if a ClusterRoleBinding binds the user to the role", func() {
+		// given
+		Expect(reader.Create(ctx, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "edit:mygroup"},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "mygroup"}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "edit"},
+		})).To(Succeed())
+
+		// when
+		names := listedNames("edit")
+
+		// then
+		Expect(names).To(ConsistOf("myns-1", "myns-2", "myns-3"))
+	})
+})
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+var _ NamespaceLister = &singleflightNamespaceLister{}
+
+// singleflightNamespaceLister deduplicates concurrent list requests for the same user identity and options,
+// so that concurrent callers share a single evaluation.
+type singleflightNamespaceLister struct {
+	lister NamespaceLister
+	group  singleflight.Group
+}
+
+func NewSingleflightNamespaceLister(lister NamespaceLister) NamespaceLister {
+	return &singleflightNamespaceLister{
+		lister: lister,
+	}
+}
+
+func (c *singleflightNamespaceLister) ListNamespaces(ctx context.Context, ui user.Info, opts ListOptions) (*corev1.NamespaceList, error) {
+	key, err := listCacheKey(ui, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		v        any
+		shared   bool
+		executed bool
+	)
+	for {
+		executed = false
+		v, err, shared = c.group.Do(key, func() (any, error) {
+			executed = true
+			return c.lister.ListNamespaces(ctx, ui, opts)
+		})
+
+		// the evaluation is bound to the context of the request that started it:
+		// if that request went away, the evaluation is restarted for the ones still waiting
+		if !executed && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		break
+	}
+	// each request is counted once, as coalesced if it did not run the evaluation it got the result of
+	listRequestsTotal.WithLabelValues(strconv.FormatBool(!executed)).Inc()
+	if err != nil {
+		return nil, err
+	}
+
+	nn := v.(*corev1.NamespaceList)
+	if shared {
+		// callers must not share the same instance
+		nn = nn.DeepCopy()
+	}
+	return nn, nil
+}
@@ -0,0 +1,162 @@
+package main_test
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// joiningUser signals when its identity is read to join an evaluation,
+// i.e. right before the request waits for the in-flight one
+type joiningUser struct {
+	user.DefaultInfo
+
+	joined chan<- struct{}
+}
+
+func (u *joiningUser) GetExtra() map[string][]string {
+	u.joined <- struct{}{}
+	return u.DefaultInfo.GetExtra()
+}
+
+var _ = Describe("SingleflightNamespaceLister", func() {
+	const followers = 5
+
+	var (
+		calls   atomic.Int32
+		started chan struct{}
+		release chan struct{}
+		nsl     namespacelister.NamespaceLister
+	)
+
+	BeforeEach(func() {
+		calls.Store(0)
+		started = make(chan struct{}, followers+1)
+		release = make(chan struct{})
+		lister := NamespaceListerMock(func(ctx context.Context, user user.Info, opts namespacelister.ListOptions) (*corev1.NamespaceList, error) {
+			calls.Add(1)
+			started <- struct{}{}
+			select {
+			case <-release:
+				return &corev1.NamespaceList{Items: []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "myns"}}}}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		nsl = namespacelister.NewSingleflightNamespaceLister(lister)
+	})
+
+	// list runs ListNamespaces in a new goroutine and returns the channel the result is sent to
+	list := func(ctx context.Context, ui user.Info) <-chan *corev1.NamespaceList {
+		r := make(chan *corev1.NamespaceList, 1)
+		go func() {
+			defer GinkgoRecover()
+			nn, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+			Expect(err).NotTo(HaveOccurred())
+			r <- nn
+		}()
+		return r
+	}
+
+	It("shares a single evaluation among concurrent requests for the same user", func() {
+		// given
+		ui := user.DefaultInfo{Name: "user", Groups: []string{"group"}}
+		rr := []<-chan *corev1.NamespaceList{list(context.Background(), &ui)}
+		Eventually(started).Should(Receive())
+		joined := make(chan struct{}, followers)
+		for range followers {
+			rr = append(rr, list(context.Background(), &joiningUser{DefaultInfo: ui, joined: joined}))
+		}
+		for range followers {
+			Eventually(joined).Should(Receive())
+		}
+
+		// when
+		close(release)
+
+		// then
+		results := []*corev1.NamespaceList{}
+		for _, r := range rr {
+			var nn *corev1.NamespaceList
+			Eventually(r).Should(Receive(&nn))
+			Expect(nn.Items).To(HaveLen(1))
+			results = append(results, nn)
+		}
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+		Expect(results[0]).NotTo(BeIdenticalTo(results[1]))
+	})
+
+	It("does not share evaluations among different users", func() {
+		// given
+		r1 := list(context.Background(), &user.DefaultInfo{Name: "user-1"})
+		r2 := list(context.Background(), &user.DefaultInfo{Name: "user-2"})
+		Eventually(started).Should(Receive())
+		Eventually(started).Should(Receive())
+
+		// when
+		close(release)
+
+		// then
+		Eventually(r1).Should(Receive())
+		Eventually(r2).Should(Receive())
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("restarts the evaluation when the request that started it is cancelled", func() {
+		// given
+		ui := &user.DefaultInfo{Name: "user"}
+		executedBefore, coalescedBefore := listRequests("false"), listRequests("true")
+		ctx, cancel := context.WithCancel(context.Background())
+		lr := make(chan error, 1)
+		go func() {
+			_, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+			lr <- err
+		}()
+		Eventually(started).Should(Receive())
+		joined := make(chan struct{}, 1)
+		fr := list(context.Background(), &joiningUser{DefaultInfo: *ui, joined: joined})
+		Eventually(joined).Should(Receive())
+
+		// when
+		cancel()
+		Eventually(lr).Should(Receive(MatchError(context.Canceled)))
+		Eventually(started).Should(Receive())
+		close(release)
+
+		// then
+		Eventually(fr).Should(Receive())
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+		// the restarted request is counted once, as it ran the evaluation it got the result of
+		Expect(listRequests("false") - executedBefore).To(BeEquivalentTo(2))
+		Expect(listRequests("true") - coalescedBefore).To(BeEquivalentTo(0))
+	})
+})
+
+// listRequests returns the value of the list requests counter for the given coalesced label
+func listRequests(coalesced string) float64 {
+	mff, err := metrics.Registry.Gather()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	for _, mf := range mff {
+		if mf.GetName() != "namespace_lister_list_requests_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "coalesced" && lp.GetValue() == coalesced {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -2,21 +2,56 @@ package main_test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
 
 	namespacelister "github.com/konflux-ci/namespace-lister"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
+// roleBindingListCounter counts the RoleBindings List requests
+type roleBindingListCounter struct {
+	client.Reader
+
+	lists int
+}
+
+func (c *roleBindingListCounter) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*rbacv1.RoleBindingList); ok {
+		c.lists++
+	}
+	return c.Reader.List(ctx, list, opts...)
+}
+
+// shuffledReader returns the namespaces in a random order, as the informer cache does
+type shuffledReader struct {
+	client.Reader
+}
+
+func (r *shuffledReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	if nn, ok := list.(*corev1.NamespaceList); ok {
+		rand.Shuffle(len(nn.Items), func(i, j int) { nn.Items[i], nn.Items[j] = nn.Items[j], nn.Items[i] })
+	}
+	return nil
+}
+
 var _ = Describe("Namespacelister", func() {
 
 	var (
@@ -35,14 +70,19 @@ var _ = Describe("Namespacelister", func() {
 		// given
 		reader := fake.NewClientBuilder().WithLists(&nn, &cr, &crb, &r, &rb).Build()
 		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
-		nsl := namespacelister.NewNamespaceLister(reader, authorizer, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+
+		insl := namespacelister.NewIndexedNamespaceLister(reader, namespacelister.NewAccessIndex(ctx, reader, logger), nil, logger)
 
 		// when
-		ann, err := nsl.ListNamespaces(ctx, "user")
+		ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
+		iann, ierr := insl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
 
 		// then
 		Expect(err).NotTo(HaveOccurred())
 		Expect(&expected).To(BeEquivalentTo(ann))
+		Expect(ierr).NotTo(HaveOccurred())
+		Expect(iann).To(BeEquivalentTo(ann))
 	},
 		Entry("returns no namespaces if no namespaces exist",
 			corev1.NamespaceList{},
@@ -149,6 +189,47 @@ var _ = Describe("Namespacelister", func() {
 			rbacv1.RoleBindingList{},
 			corev1.NamespaceList{Items: []corev1.Namespace{}},
 		),
+		Entry("returns all the namespaces if a clusterrolebinding grants access to all of them",
+			corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules: []rbacv1.PolicyRule{
+						{
+							APIGroups: []string{"*"},
+							Verbs:     []string{"*"},
+							Resources: []string{"*"},
+						},
+					},
+				},
+			}},
+			rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user"},
+					Subjects: []rbacv1.Subject{
+						{
+							APIGroup: rbacv1.GroupName,
+							Kind:     "User",
+							Name:     "user",
+						},
+					},
+					RoleRef: rbacv1.RoleRef{
+						Kind:     "ClusterRole",
+						APIGroup: rbacv1.GroupName,
+						Name:     "ns-get",
+					},
+				},
+			}},
+			rbacv1.RoleList{},
+			rbacv1.RoleBindingList{},
+			corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1", ResourceVersion: "999"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2", ResourceVersion: "999"}},
+			}},
+		),
 		Entry("returns a namespace if both the role and the rolebinding exist",
 			corev1.NamespaceList{Items: []corev1.Namespace{
 				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
@@ -193,4 +274,375 @@ var _ = Describe("Namespacelister", func() {
 			}},
 		),
 	)
+
+	It("returns the namespaces a group member has access to", func() {
+		// given
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-3"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules: []rbacv1.PolicyRule{
+						{
+							APIGroups: []string{""},
+							Verbs:     []string{"get"},
+							Resources: []string{"namespaces"},
+						},
+					},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:mygroup", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "mygroup"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:authenticated", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "system:authenticated"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:othergroup", Namespace: "myns-3"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "othergroup"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+
+		insl := namespacelister.NewIndexedNamespaceLister(reader, namespacelister.NewAccessIndex(ctx, reader, logger), nil, logger)
+		ui := &user.DefaultInfo{
+			Name:   "user",
+			Groups: []string{"mygroup", "system:authenticated"},
+		}
+
+		// when
+		ann, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		iann, ierr := insl.ListNamespaces(ctx, ui, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ann.Items).To(HaveLen(2))
+		Expect(ann.Items[0].Name).To(Equal("myns-1"))
+		Expect(ann.Items[1].Name).To(Equal("myns-2"))
+		Expect(ierr).NotTo(HaveOccurred())
+		Expect(iann).To(Equal(ann))
+	})
+
+	It("returns the namespaces a ServiceAccount has access to", func() {
+		// given
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-3"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules: []rbacv1.PolicyRule{
+						{
+							APIGroups: []string{""},
+							Verbs:     []string{"get"},
+							Resources: []string{"namespaces"},
+						},
+					},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:mysa", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "mysa", Namespace: "mysa-ns"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:mysa-ns", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "system:serviceaccounts:mysa-ns"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:othersa", Namespace: "myns-3"},
+					Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "othersa", Namespace: "mysa-ns"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+
+		insl := namespacelister.NewIndexedNamespaceLister(reader, namespacelister.NewAccessIndex(ctx, reader, logger), nil, logger)
+		ui := &user.DefaultInfo{
+			Name:   "system:serviceaccount:mysa-ns:mysa",
+			Groups: []string{"system:serviceaccounts", "system:serviceaccounts:mysa-ns", "system:authenticated"},
+		}
+
+		// when
+		ann, err := nsl.ListNamespaces(ctx, ui, defaultListOptions)
+		iann, ierr := insl.ListNamespaces(ctx, ui, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ann.Items).To(HaveLen(2))
+		Expect(ann.Items[0].Name).To(Equal("myns-1"))
+		Expect(ann.Items[1].Name).To(Equal("myns-2"))
+		Expect(ierr).NotTo(HaveOccurred())
+		Expect(iann).To(Equal(ann))
+	})
+
+	It("returns all namespaces without evaluating each of them if the user has cluster-wide access", func() {
+		// given
+		reader := &roleBindingListCounter{Reader: fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules: []rbacv1.PolicyRule{
+						{
+							APIGroups: []string{""},
+							Verbs:     []string{"get"},
+							Resources: []string{"namespaces"},
+						},
+					},
+				},
+			}},
+			&rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:admins"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "admins"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()}
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+
+		// when
+		ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user", Groups: []string{"admins"}}, defaultListOptions)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ann.Items).To(HaveLen(2))
+		Expect(reader.lists).To(BeZero())
+	})
+	It("returns the namespaces in which the user has the requested access", func() {
+		// given
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "deployer"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Verbs: []string{"create"}, Resources: []string{"deployments"}}},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "deployer:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "deployer"},
+				},
+			}},
+		).Build()
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+		insl := namespacelister.NewIndexedNamespaceLister(reader, namespacelister.NewAccessIndex(ctx, reader, logger), nsl, logger)
+		opts := namespacelister.ListOptions{Access: namespacelister.AccessCheck{Verb: "create", Resource: "deployments", APIGroup: "apps"}}
+
+		// when
+		ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, opts)
+		iann, ierr := insl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, opts)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ann.Items).To(HaveLen(1))
+		Expect(ann.Items[0].Name).To(Equal("myns-2"))
+		Expect(ierr).NotTo(HaveOccurred())
+		Expect(iann).To(Equal(ann))
+	})
+
+	It("returns the namespaces matching the selectors the user has access to", func() {
+		// given
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1", Labels: map[string]string{"konflux.ci/type": "user"}}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2", Labels: map[string]string{"konflux.ci/type": "user"}}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-3", Labels: map[string]string{"konflux.ci/type": "user"}}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-4"}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}}},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-4"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+		insl := namespacelister.NewIndexedNamespaceLister(reader, namespacelister.NewAccessIndex(ctx, reader, logger), nil, logger)
+		opts := namespacelister.ListOptions{
+			Access:        namespacelister.DefaultAccessCheck,
+			LabelSelector: "konflux.ci/type=user",
+			FieldSelector: "status.phase=Active",
+		}
+
+		// when
+		ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, opts)
+		iann, ierr := insl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, opts)
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ann.Items).To(HaveLen(1))
+		Expect(ann.Items[0].Name).To(Equal("myns-1"))
+		Expect(ierr).NotTo(HaveOccurred())
+		Expect(iann).To(Equal(ann))
+	})
+
+	It("rejects selectors on unsupported fields", func() {
+		// given
+		reader := fake.NewClientBuilder().Build()
+		authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+		nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 4}, logger)
+		opts := namespacelister.ListOptions{Access: namespacelister.DefaultAccessCheck, FieldSelector: "metadata.namespace=myns"}
+
+		// when
+		_, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, opts)
+
+		// then
+		Expect(kerrors.IsBadRequest(err)).To(BeTrue())
+	})
+
+	Context("when evaluating many namespaces", func() {
+		var reader client.Reader
+
+		BeforeEach(func() {
+			nn := corev1.NamespaceList{}
+			rbb := rbacv1.RoleBindingList{}
+			for i := range 50 {
+				name := fmt.Sprintf("myns-%02d", i)
+				nn.Items = append(nn.Items, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+				if i%2 == 0 {
+					rbb.Items = append(rbb.Items, rbacv1.RoleBinding{
+						ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: name},
+						Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+						RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+					})
+				}
+			}
+
+			reader = fake.NewClientBuilder().WithLists(
+				&nn,
+				&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+						Rules: []rbacv1.PolicyRule{
+							{
+								APIGroups: []string{""},
+								Verbs:     []string{"get"},
+								Resources: []string{"namespaces"},
+							},
+						},
+					},
+				}},
+				&rbb,
+			).Build()
+		})
+
+		It("returns the namespaces in a deterministic order", func() {
+			// given
+			authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+			nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 8}, logger)
+
+			// when
+			ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ann.Items).To(HaveLen(25))
+			for i, ns := range ann.Items {
+				Expect(ns.Name).To(Equal(fmt.Sprintf("myns-%02d", i*2)))
+			}
+		})
+
+		It("returns the namespaces sorted by name whatever the order of the cache", func() {
+			// given
+			sr := &shuffledReader{Reader: reader}
+			authorizer := namespacelister.NewAuthorizer(ctx, sr, logger)
+			nsl := namespacelister.NewNamespaceLister(sr, authorizer, namespacelister.NamespaceListerOptions{Workers: 8}, logger)
+
+			for range 5 {
+				// when
+				ann, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ann.Items).To(HaveLen(25))
+				for i, ns := range ann.Items {
+					Expect(ns.Name).To(Equal(fmt.Sprintf("myns-%02d", i*2)))
+				}
+			}
+		})
+
+		It("stops when the request is cancelled", func() {
+			// given
+			authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+			nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 8}, logger)
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			// when
+			_, err := nsl.ListNamespaces(cctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
+
+			// then
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("returns a timeout error when the deadline passes", func() {
+			// given
+			authorizer := namespacelister.NewAuthorizer(ctx, reader, logger)
+			nsl := namespacelister.NewNamespaceLister(reader, authorizer, namespacelister.NamespaceListerOptions{Workers: 8, Timeout: time.Nanosecond}, logger)
+
+			// when
+			_, err := nsl.ListNamespaces(ctx, &user.DefaultInfo{Name: "user"}, defaultListOptions)
+
+			// then
+			Expect(kerrors.IsTimeout(err)).To(BeTrue())
+		})
+	})
 })
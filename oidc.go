@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+)
+
+const (
+	// oidcClockSkew is the leeway allowed when validating the time-based claims
+	oidcClockSkew = 1 * time.Minute
+	// oidcJWKSMinRefreshInterval is the minimum interval between two fetches of a remote JWKS
+	oidcJWKSMinRefreshInterval = 1 * time.Minute
+	// oidcReservedPrefix is the prefix of the identities reserved to Kubernetes
+	oidcReservedPrefix = "system:"
+)
+
+// oidcSupportedAlgorithms are the signature algorithms accepted in ID tokens
+var oidcSupportedAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+}
+
+var _ authenticator.Token = &oidcAuthenticator{}
+
+// OIDCOptions configures the validation of OIDC ID tokens.
+// Claim mapping follows kube-apiserver's `--oidc-*` flags.
+type OIDCOptions struct {
+	// IssuerURL is the expected value of the `iss` claim
+	IssuerURL string
+	// Audience is the value the `aud` claim is expected to contain
+	Audience string
+	// UsernameClaim is the claim containing the username, defaults to `sub`
+	UsernameClaim string
+	// UsernamePrefix is prepended to the username.
+	// If not set and UsernameClaim is not `email`, the prefix is `<IssuerURL>#`.
+	// Use `-` to disable prefixing.
+	UsernamePrefix string
+	// GroupsClaim is the claim containing the user's groups, if not set groups are not read from the token
+	GroupsClaim string
+	// GroupsPrefix is prepended to every group
+	GroupsPrefix string
+}
+
+// JWKSProvider provides the keys used to verify the ID tokens
+type JWKSProvider interface {
+	// KeySet returns the keys to use for verifying a token signed with the given key ID.
+	KeySet(ctx context.Context, keyID string) (*jose.JSONWebKeySet, error)
+}
+
+// NewOIDCAuthenticator builds an authenticator that validates the request's Bearer token
+// as an OIDC ID token signed with one of the keys provided by jwks.
+func NewOIDCAuthenticator(opts OIDCOptions, jwks JWKSProvider) (authenticator.Request, error) {
+	if opts.IssuerURL == "" {
+		return nil, errors.New("OIDC issuer URL is required")
+	}
+	if opts.Audience == "" {
+		return nil, errors.New("OIDC audience is required")
+	}
+	if opts.UsernameClaim == "" {
+		opts.UsernameClaim = "sub"
+	}
+
+	switch {
+	case opts.UsernamePrefix == "-":
+		opts.UsernamePrefix = ""
+	case opts.UsernamePrefix == "" && opts.UsernameClaim != "email":
+		opts.UsernamePrefix = opts.IssuerURL + "#"
+	}
+
+	return bearertoken.New(&oidcAuthenticator{opts: opts, jwks: jwks, now: time.Now}), nil
+}
+
+type oidcAuthenticator struct {
+	opts OIDCOptions
+	jwks JWKSProvider
+	now  func() time.Time
+}
+
+func (a *oidcAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	tok, err := jwt.ParseSigned(token, oidcSupportedAlgorithms)
+	if err != nil {
+		// not a JWT
+		return nil, false, nil
+	}
+
+	// verify the signature
+	claims, raw, err := a.verify(ctx, tok)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// validate the claims
+	if claims.Expiry == nil {
+		return nil, false, errors.New("oidc: token has no expiration")
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:      a.opts.IssuerURL,
+		AnyAudience: jwt.Audience{a.opts.Audience},
+		Time:        a.now(),
+	}, oidcClockSkew); err != nil {
+		return nil, false, fmt.Errorf("oidc: invalid token: %w", err)
+	}
+
+	// map the claims to the user
+	username, err := a.username(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	groups, err := a.groups(raw)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// as kube-apiserver does, tokens can not claim the reserved `system:` identities,
+	// e.g. a ServiceAccount username would grant the implicit ServiceAccount groups
+	if strings.HasPrefix(username, oidcReservedPrefix) {
+		return nil, false, fmt.Errorf("oidc: username %q must not start with %q", username, oidcReservedPrefix)
+	}
+	for _, g := range groups {
+		if strings.HasPrefix(g, oidcReservedPrefix) {
+			return nil, false, fmt.Errorf("oidc: group %q must not start with %q", g, oidcReservedPrefix)
+		}
+	}
+	return &authenticator.Response{User: newUserInfo(username, "", groups, nil)}, true, nil
+}
+
+// verify verifies the token signature and returns its claims
+func (a *oidcAuthenticator) verify(ctx context.Context, tok *jwt.JSONWebToken) (*jwt.Claims, map[string]any, error) {
+	keyID := ""
+	if len(tok.Headers) > 0 {
+		keyID = tok.Headers[0].KeyID
+	}
+
+	ks, err := a.jwks.KeySet(ctx, keyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc: error retrieving keys: %w", err)
+	}
+
+	keys := ks.Keys
+	if keyID != "" {
+		keys = ks.Key(keyID)
+	}
+	for _, k := range keys {
+		claims, raw := jwt.Claims{}, map[string]any{}
+		if err := tok.Claims(k.Key, &claims, &raw); err == nil {
+			return &claims, raw, nil
+		}
+	}
+	return nil, nil, errors.New("oidc: failed to verify token signature")
+}
+
+// username returns the prefixed username from the configured claim
+func (a *oidcAuthenticator) username(raw map[string]any) (string, error) {
+	username, ok := raw[a.opts.UsernameClaim].(string)
+	if !ok || username == "" {
+		return "", fmt.Errorf("oidc: claim %q not present or not a string", a.opts.UsernameClaim)
+	}
+
+	// as kube-apiserver does, the email is trusted only if verified
+	if a.opts.UsernameClaim == "email" {
+		if v, ok := raw["email_verified"]; ok {
+			if verified, ok := v.(bool); !ok || !verified {
+				return "", errors.New("oidc: email not verified")
+			}
+		}
+	}
+
+	return a.opts.UsernamePrefix + username, nil
+}
+
+// groups returns the prefixed groups from the configured claim.
+// The claim can be either a string or a list of strings.
+func (a *oidcAuthenticator) groups(raw map[string]any) ([]string, error) {
+	if a.opts.GroupsClaim == "" {
+		return nil, nil
+	}
+
+	var gg []string
+	switch v := raw[a.opts.GroupsClaim].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		gg = []string{v}
+	case []any:
+		for _, g := range v {
+			s, ok := g.(string)
+			if !ok {
+				return nil, fmt.Errorf("oidc: claim %q is not a list of strings", a.opts.GroupsClaim)
+			}
+			gg = append(gg, s)
+		}
+	default:
+		return nil, fmt.Errorf("oidc: claim %q is not a string or a list of strings", a.opts.GroupsClaim)
+	}
+
+	for i, g := range gg {
+		gg[i] = a.opts.GroupsPrefix + g
+	}
+	return gg, nil
+}
+
+// StaticJWKSProvider provides a fixed set of keys
+type StaticJWKSProvider struct {
+	keys *jose.JSONWebKeySet
+}
+
+// NewFileJWKSProvider reads the keys from a JWKS file
+func NewFileJWKSProvider(path string) (*StaticJWKSProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWKS file: %w", err)
+	}
+
+	ks, err := parseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticJWKSProvider{keys: ks}, nil
+}
+
+func (p *StaticJWKSProvider) KeySet(_ context.Context, _ string) (*jose.JSONWebKeySet, error) {
+	return p.keys, nil
+}
+
+// RemoteJWKSProvider fetches the keys from a JWKS URL.
+// The keys are fetched again when a token is signed with an unknown key,
+// at most once every minute, failed fetches included.
+type RemoteJWKSProvider struct {
+	url string
+	cli *http.Client
+
+	mu          sync.Mutex
+	keys        *jose.JSONWebKeySet
+	lastErr     error
+	lastFetched time.Time
+}
+
+func NewRemoteJWKSProvider(url string, cli *http.Client) *RemoteJWKSProvider {
+	return &RemoteJWKSProvider{
+		url: url,
+		cli: cli,
+	}
+}
+
+func (p *RemoteJWKSProvider) KeySet(ctx context.Context, keyID string) (*jose.JSONWebKeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	known := p.keys != nil && (keyID == "" || len(p.keys.Key(keyID)) > 0)
+	if !known && time.Since(p.lastFetched) >= oidcJWKSMinRefreshInterval {
+		p.lastFetched = time.Now()
+		if ks, err := p.fetch(ctx); err != nil {
+			p.lastErr = err
+		} else {
+			p.keys, p.lastErr = ks, nil
+		}
+	}
+
+	// keep using the last keys fetched if the refresh failed
+	if p.keys == nil {
+		return nil, p.lastErr
+	}
+	return p.keys, nil
+}
+
+func (p *RemoteJWKSProvider) fetch(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := p.cli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching JWKS: %w", err)
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching JWKS: unexpected status %s", rs.Status)
+	}
+
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching JWKS: %w", err)
+	}
+	return parseJWKS(b)
+}
+
+func parseJWKS(b []byte) (*jose.JSONWebKeySet, error) {
+	ks := jose.JSONWebKeySet{}
+	if err := json.Unmarshal(b, &ks); err != nil {
+		return nil, fmt.Errorf("error parsing JWKS: %w", err)
+	}
+	if len(ks.Keys) == 0 {
+		return nil, errors.New("error parsing JWKS: no keys found")
+	}
+	return &ks, nil
+}
+
+// newJWKSProvider builds a JWKSProvider from the given location, either a file path or an HTTP(S) URL
+func newJWKSProvider(location string) (JWKSProvider, error) {
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		return NewRemoteJWKSProvider(location, &http.Client{Timeout: 10 * time.Second}), nil
+	}
+	return NewFileJWKSProvider(location)
+}
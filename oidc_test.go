@@ -0,0 +1,233 @@
+package main_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	namespacelister "github.com/konflux-ci/namespace-lister"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+)
+
+var _ = Describe("OIDCAuthenticator", func() {
+	const (
+		issuer   = "https://issuer.example.com"
+		audience = "namespace-lister"
+		keyID    = "mykey"
+	)
+
+	var (
+		key      *rsa.PrivateKey
+		jwksPath string
+	)
+
+	sign := func(k *rsa.PrivateKey, kid string, claims map[string]any) string {
+		s, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: k},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		t, err := jwt.Signed(s).Claims(claims).Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss":    issuer,
+			"aud":    audience,
+			"sub":    "mysub",
+			"email":  "user@example.com",
+			"groups": []string{"mygroup-1", "mygroup-2"},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"iat":    time.Now().Unix(),
+		}
+	}
+
+	authenticate := func(authn authenticator.Request, token string) (*authenticator.Response, bool, error) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add("Authorization", "Bearer "+token)
+		return authn.AuthenticateRequest(r)
+	}
+
+	BeforeEach(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: key.Public(), KeyID: keyID, Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		jwksPath = filepath.Join(GinkgoT().TempDir(), "jwks.json")
+		Expect(os.WriteFile(jwksPath, b, 0o600)).To(Succeed())
+	})
+
+	DescribeTable("maps the claims to the user", func(opts namespacelister.OIDCOptions, expectedUsername string, expectedGroups []string) {
+		// given
+		opts.IssuerURL, opts.Audience = issuer, audience
+		jwks, err := namespacelister.NewFileJWKSProvider(jwksPath)
+		Expect(err).NotTo(HaveOccurred())
+		authn, err := namespacelister.NewOIDCAuthenticator(opts, jwks)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		rs, ok, err := authenticate(authn, sign(key, keyID, validClaims()))
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal(expectedUsername))
+		Expect(rs.User.GetGroups()).To(Equal(expectedGroups))
+	},
+		Entry("default claims",
+			namespacelister.OIDCOptions{},
+			issuer+"#mysub", []string{"system:authenticated"}),
+		Entry("email claim is not prefixed by default",
+			namespacelister.OIDCOptions{UsernameClaim: "email", GroupsClaim: "groups"},
+			"user@example.com", []string{"mygroup-1", "mygroup-2", "system:authenticated"}),
+		Entry("custom prefixes",
+			namespacelister.OIDCOptions{UsernamePrefix: "oidc:", GroupsClaim: "groups", GroupsPrefix: "oidc:"},
+			"oidc:mysub", []string{"oidc:mygroup-1", "oidc:mygroup-2", "system:authenticated"}),
+		Entry("disabled prefix",
+			namespacelister.OIDCOptions{UsernamePrefix: "-"},
+			"mysub", []string{"system:authenticated"}),
+	)
+
+	DescribeTable("rejects invalid tokens", func(mutate func(map[string]any), signingKey func() *rsa.PrivateKey) {
+		// given
+		jwks, err := namespacelister.NewFileJWKSProvider(jwksPath)
+		Expect(err).NotTo(HaveOccurred())
+		authn, err := namespacelister.NewOIDCAuthenticator(namespacelister.OIDCOptions{
+			IssuerURL:     issuer,
+			Audience:      audience,
+			UsernameClaim: "email",
+		}, jwks)
+		Expect(err).NotTo(HaveOccurred())
+
+		claims := validClaims()
+		mutate(claims)
+		k := key
+		if signingKey != nil {
+			k = signingKey()
+		}
+
+		// when
+		_, ok, err := authenticate(authn, sign(k, keyID, claims))
+
+		// then
+		Expect(ok).To(BeFalse())
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, nil),
+		Entry("without expiration", func(c map[string]any) { delete(c, "exp") }, nil),
+		Entry("wrong issuer", func(c map[string]any) { c["iss"] = "https://other.example.com" }, nil),
+		Entry("wrong audience", func(c map[string]any) { c["aud"] = "other" }, nil),
+		Entry("unverified email", func(c map[string]any) { c["email_verified"] = false }, nil),
+		Entry("missing username claim", func(c map[string]any) { delete(c, "email") }, nil),
+		Entry("reserved username", func(c map[string]any) { c["email"] = "system:serviceaccount:myns:mysa" }, nil),
+		Entry("unknown signing key", func(map[string]any) {}, func() *rsa.PrivateKey {
+			k, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			return k
+		}),
+	)
+
+	It("retrieves the keys from a JWKS URL", func() {
+		// given
+		b, err := os.ReadFile(jwksPath)
+		Expect(err).NotTo(HaveOccurred())
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(b)
+		}))
+		DeferCleanup(srv.Close)
+
+		authn, err := namespacelister.NewOIDCAuthenticator(namespacelister.OIDCOptions{
+			IssuerURL:      issuer,
+			Audience:       audience,
+			UsernamePrefix: "oidc:",
+		}, namespacelister.NewRemoteJWKSProvider(srv.URL, srv.Client()))
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		rs, ok, err := authenticate(authn, sign(key, keyID, validClaims()))
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(rs.User.GetName()).To(Equal("oidc:mysub"))
+	})
+
+	It("rejects tokens claiming reserved groups", func() {
+		// given
+		jwks, err := namespacelister.NewFileJWKSProvider(jwksPath)
+		Expect(err).NotTo(HaveOccurred())
+		authn, err := namespacelister.NewOIDCAuthenticator(namespacelister.OIDCOptions{
+			IssuerURL:   issuer,
+			Audience:    audience,
+			GroupsClaim: "groups",
+		}, jwks)
+		Expect(err).NotTo(HaveOccurred())
+		claims := validClaims()
+		claims["groups"] = []string{"mygroup-1", "system:masters"}
+
+		// when
+		_, ok, err := authenticate(authn, sign(key, keyID, claims))
+
+		// then
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not fetch the keys again right after a failed fetch", func() {
+		// given
+		var fetches atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		DeferCleanup(srv.Close)
+
+		authn, err := namespacelister.NewOIDCAuthenticator(namespacelister.OIDCOptions{
+			IssuerURL: issuer,
+			Audience:  audience,
+		}, namespacelister.NewRemoteJWKSProvider(srv.URL, srv.Client()))
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		_, ok1, err1 := authenticate(authn, sign(key, keyID, validClaims()))
+		_, ok2, err2 := authenticate(authn, sign(key, keyID, validClaims()))
+
+		// then
+		Expect(ok1).To(BeFalse())
+		Expect(err1).To(HaveOccurred())
+		Expect(ok2).To(BeFalse())
+		Expect(err2).To(HaveOccurred())
+		Expect(fetches.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("rejects tokens that are not JWTs", func() {
+		// given
+		jwks, err := namespacelister.NewFileJWKSProvider(jwksPath)
+		Expect(err).NotTo(HaveOccurred())
+		authn, err := namespacelister.NewOIDCAuthenticator(namespacelister.OIDCOptions{IssuerURL: issuer, Audience: audience}, jwks)
+		Expect(err).NotTo(HaveOccurred())
+
+		// when
+		_, ok, _ := authenticate(authn, "not-a-jwt")
+
+		// then
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -8,6 +8,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"k8s.io/kubernetes/pkg/registry/rbac/validation"
 	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
 )
 
@@ -17,6 +18,27 @@ func NewAuthorizer(ctx context.Context, cli client.Reader, l *slog.Logger) *rbac
 	return ra
 }
 
+// NewRuleResolver builds an RBAC rule resolver backed by the given reader
+func NewRuleResolver(ctx context.Context, cli client.Reader, l *slog.Logger) *validation.DefaultRuleResolver {
+	aur := NewCRAuthRetriever(ctx, cli, l)
+	return validation.NewDefaultRuleResolver(aur, aur, aur, aur)
+}
+
+// NewRoleBindingRuleResolver builds an RBAC rule resolver backed by the given reader
+// that ignores the ClusterRoleBindings, i.e. it only resolves the rules granted in a namespace.
+// It allows resolving the ClusterRoleBindings' rules once for many namespaces.
+func NewRoleBindingRuleResolver(ctx context.Context, cli client.Reader, l *slog.Logger) *validation.DefaultRuleResolver {
+	aur := NewCRAuthRetriever(ctx, cli, l)
+	return validation.NewDefaultRuleResolver(aur, aur, aur, noClusterRoleBindings{})
+}
+
+// noClusterRoleBindings lists no ClusterRoleBinding
+type noClusterRoleBindings struct{}
+
+func (noClusterRoleBindings) ListClusterRoleBindings() ([]*rbacv1.ClusterRoleBinding, error) {
+	return nil, nil
+}
+
 type CRAuthRetriever struct {
 	cli client.Reader
 	ctx context.Context
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+)
+
+// NamespaceSubjects lists the subjects allowed to get a namespace
+type NamespaceSubjects struct {
+	Namespace string `json:"namespace"`
+	// Subjects are the users, groups, and ServiceAccounts allowed to get the namespace,
+	// once for each binding granting them the access
+	Subjects []SubjectAccess `json:"subjects"`
+	// Errors are the errors encountered resolving the roles of the bindings
+	Errors []string `json:"errors,omitempty"`
+}
+
+// SubjectAccess is a subject allowed to get a namespace and the grant of the access
+type SubjectAccess struct {
+	Subject   rbacv1.Subject `json:"subject"`
+	GrantedBy Grant          `json:"grantedBy"`
+}
+
+// SubjectsFor returns the subjects the ClusterRoleBindings and the RoleBindings in the namespace
+// grant `get` access on the namespace to
+func (e *Explainer) SubjectsFor(ctx context.Context, namespace string) (*NamespaceSubjects, error) {
+	if _, err := getNamespace(ctx, e.reader, namespace); err != nil {
+		return nil, err
+	}
+
+	aur := NewCRAuthRetriever(ctx, e.reader, e.l)
+	bb, err := listBindings(aur, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := DefaultAccessCheck.Attributes(namespace)
+	resolver := NewRuleResolver(ctx, e.reader, e.l)
+	nss := &NamespaceSubjects{Namespace: namespace, Subjects: []SubjectAccess{}}
+	for _, b := range bb {
+		rr, err := resolver.GetRoleReferenceRules(b.RoleRef, b.Namespace)
+		if err != nil {
+			nss.Errors = append(nss.Errors, fmt.Sprintf("%s: %v", b, err))
+			continue
+		}
+
+		for _, r := range rr {
+			if !rbac.RuleAllows(attrs, &r) {
+				continue
+			}
+
+			for _, s := range b.subjects {
+				// as the RBAC authorizer does, ServiceAccounts without namespace default to the binding's one
+				if s.Kind == rbacv1.ServiceAccountKind && s.Namespace == "" {
+					if b.Namespace == "" {
+						continue
+					}
+					s.Namespace = b.Namespace
+				}
+				nss.Subjects = append(nss.Subjects, SubjectAccess{
+					Subject:   s,
+					GrantedBy: Grant{Binding: b.BindingReference, Rule: r},
+				})
+			}
+			break
+		}
+	}
+	return nss, nil
+}
@@ -0,0 +1,115 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	namespacelister "github.com/konflux-ci/namespace-lister"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Explainer.SubjectsFor", func() {
+	var (
+		ctx    = context.TODO()
+		logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+		explainer *namespacelister.Explainer
+	)
+
+	BeforeEach(func() {
+		reader := fake.NewClientBuilder().WithLists(
+			&corev1.NamespaceList{Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "myns-2"}},
+			}},
+			&rbacv1.ClusterRoleList{Items: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"namespaces"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"},
+					Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Verbs: []string{"get"}, Resources: []string{"pods"}}},
+				},
+			}},
+			&rbacv1.ClusterRoleBindingList{Items: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:admins"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "admins"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+			&rbacv1.RoleBindingList{Items: []rbacv1.RoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:users", Namespace: "myns-1"},
+					Subjects: []rbacv1.Subject{
+						{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"},
+						{Kind: "ServiceAccount", Name: "mysa"},
+					},
+					RoleRef: rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-reader:other", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "other"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "pod-reader"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing:other", Namespace: "myns-1"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "other"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "missing"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ns-get:user", Namespace: "myns-2"},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"}},
+					RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "ns-get"},
+				},
+			}},
+		).Build()
+		explainer = namespacelister.NewExplainer(&cacheReader{Reader: reader}, logger)
+	})
+
+	It("returns the subjects allowed to get the namespace and the granting bindings", func() {
+		// when
+		nss, err := explainer.SubjectsFor(ctx, "myns-1")
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nss.Namespace).To(Equal("myns-1"))
+
+		subjects := []rbacv1.Subject{}
+		bindings := []string{}
+		for _, s := range nss.Subjects {
+			subjects = append(subjects, s.Subject)
+			bindings = append(bindings, s.GrantedBy.Binding.Name)
+			Expect(s.GrantedBy.Rule.Resources).To(ConsistOf("namespaces"))
+		}
+		Expect(subjects).To(Equal([]rbacv1.Subject{
+			{APIGroup: rbacv1.GroupName, Kind: "Group", Name: "admins"},
+			{APIGroup: rbacv1.GroupName, Kind: "User", Name: "user"},
+			{Kind: "ServiceAccount", Name: "mysa", Namespace: "myns-1"},
+		}))
+		Expect(bindings).To(Equal([]string{"ns-get:admins", "ns-get:users", "ns-get:users"}))
+		Expect(nss.Errors).To(HaveLen(1))
+		Expect(nss.Errors[0]).To(ContainSubstring("missing:other"))
+	})
+
+	It("returns NotFound if the namespace does not exist", func() {
+		// when
+		_, err := explainer.SubjectsFor(ctx, "notfound")
+
+		// then
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		Expect(err).To(MatchError(`namespaces "notfound" not found`))
+		Expect(err.(kerrors.APIStatus).Status().Details.Kind).To(Equal("namespaces"))
+	})
+})
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// UserHeaders declares the request headers carrying the user identity
+type UserHeaders struct {
+	// Username is the header containing the username
+	Username string
+	// UID is the header containing the user's UID
+	UID string
+	// Groups is the header containing the user's groups
+	Groups string
+	// ExtraPrefixes are the prefixes of the headers containing the user's extra attributes,
+	// e.g. with `Impersonate-Extra-` the header `Impersonate-Extra-Scopes` sets the extra `scopes`
+	ExtraPrefixes []string
+}
+
+// userInfoFromHeaders builds the user identity from the request headers
+func userInfoFromHeaders(h http.Header, uh UserHeaders) user.Info {
+	return newUserInfo(
+		h.Get(uh.Username),
+		headerValue(h, uh.UID),
+		groupsFromHeader(h, uh.Groups),
+		extraFromHeaders(h, uh.ExtraPrefixes),
+	)
+}
+
+// newUserInfo builds the identity used to evaluate user access.
+// As kube-apiserver does for authenticated requests, the user is
+// always added to the `system:authenticated` group.
+// ServiceAccount usernames (`system:serviceaccount:<ns>:<name>`) are
+// also added to the implicit `system:serviceaccounts` and
+// `system:serviceaccounts:<ns>` groups.
+func newUserInfo(username, uid string, groups []string, extra map[string][]string) user.Info {
+	if ns, _, err := serviceaccount.SplitUsername(username); err == nil {
+		groups = appendMissing(groups, serviceaccount.MakeGroupNames(ns)...)
+	}
+	groups = appendMissing(groups, user.AllAuthenticated)
+
+	return &user.DefaultInfo{
+		Name:   username,
+		UID:    uid,
+		Groups: groups,
+		Extra:  extra,
+	}
+}
+
+// headerValue returns the value of the given header, if the header is declared
+func headerValue(h http.Header, header string) string {
+	if header == "" {
+		return ""
+	}
+	return h.Get(header)
+}
+
+// groupsFromHeader reads the groups from the given header.
+// The header can be repeated and each value can contain a comma-separated list of groups.
+func groupsFromHeader(h http.Header, header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	gg := []string{}
+	for _, v := range h.Values(header) {
+		for _, g := range strings.Split(v, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				gg = appendMissing(gg, g)
+			}
+		}
+	}
+	return gg
+}
+
+// extraFromHeaders reads the user's extra attributes from the headers matching the given prefixes.
+// As kube-apiserver does, keys are lower-cased and percent-decoded.
+func extraFromHeaders(h http.Header, prefixes []string) map[string][]string {
+	extra := map[string][]string{}
+	for k, vv := range h {
+		for _, p := range prefixes {
+			if p == "" || len(k) <= len(p) || !strings.EqualFold(k[:len(p)], p) {
+				continue
+			}
+
+			ek := strings.ToLower(k[len(p):])
+			if uk, err := url.PathUnescape(ek); err == nil {
+				ek = uk
+			}
+			extra[ek] = append(extra[ek], vv...)
+			break
+		}
+	}
+
+	if len(extra) == 0 {
+		return nil
+	}
+	return extra
+}
+
+// appendMissing appends to ss the values it does not already contain
+func appendMissing(ss []string, vv ...string) []string {
+	for _, v := range vv {
+		if !slices.Contains(ss, v) {
+			ss = append(ss, v)
+		}
+	}
+	return ss
+}